@@ -0,0 +1,54 @@
+package cpu
+
+// C 指令 111a cccc ccdd djjj 中各部分的位
+const (
+	BIT_A     = 1 << 12
+	BIT_ZX    = 1 << 11
+	BIT_NX    = 1 << 10
+	BIT_ZY    = 1 << 9
+	BIT_NY    = 1 << 8
+	BIT_F     = 1 << 7
+	BIT_NO    = 1 << 6
+	DEST_A    = 1 << 5
+	DEST_D    = 1 << 4
+	DEST_M    = 1 << 3
+	JUMP_LT   = 1 << 2
+	JUMP_EQ   = 1 << 1
+	JUMP_GT   = 1 << 0
+	JUMP_MASK = JUMP_LT | JUMP_EQ | JUMP_GT
+)
+
+// ALU 按 C 指令中的 zx nx zy ny f no 六个控制位计算，x 是 D，y 是 A 或 M。
+// 和 05/CPU.hdl 中的 ALU 一样，汇编器 comp 表中的每个助记符都对应这样一组控制位
+func ALU(instruction uint16, x uint16, y uint16) uint16 {
+	if instruction&BIT_ZX != 0 {
+		x = 0
+	}
+	if instruction&BIT_NX != 0 {
+		x = ^x
+	}
+	if instruction&BIT_ZY != 0 {
+		y = 0
+	}
+	if instruction&BIT_NY != 0 {
+		y = ^y
+	}
+	var out uint16
+	if instruction&BIT_F != 0 {
+		out = x + y
+	} else {
+		out = x & y
+	}
+	if instruction&BIT_NO != 0 {
+		out = ^out
+	}
+	return out
+}
+
+// Jumps 判断 ALU 的输出 out 是否满足 C 指令的跳转条件
+func Jumps(instruction uint16, out uint16) bool {
+	value := int16(out)
+	return instruction&JUMP_LT != 0 && value < 0 ||
+		instruction&JUMP_EQ != 0 && value == 0 ||
+		instruction&JUMP_GT != 0 && value > 0
+}
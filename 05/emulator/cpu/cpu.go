@@ -0,0 +1,191 @@
+// Package cpu 实现 Hack 计算机的 CPU 模拟器：32K ROM、A/D/PC 寄存器、32K RAM，
+// 以及映射到 RAM 中的屏幕和键盘。程序可以是 06/assembler 生成的 .hack，也可以直接是 .asm
+package cpu
+
+import (
+	"fmt"
+
+	"hongkuancn/nand2tetris/assembler/asm"
+)
+
+const (
+	ROM_SIZE = asm.ROM_SIZE
+	RAM_SIZE = 32768
+	SCREEN   = asm.SCREEN
+	// SCREEN_SIZE 是屏幕占用的字数，512x256 个像素每字 16 个
+	SCREEN_SIZE = 8192
+	KBD         = asm.KBD
+	// ADDRESS_MASK 是地址总线的宽度，A 的最高位不参与寻址
+	ADDRESS_MASK = 0x7fff
+)
+
+// Run 结束的原因
+const (
+	// HALT_LOOP 表示程序进入了 (END) @END 0;JMP 这样不会再改变状态的死循环
+	HALT_LOOP = "halted"
+	// HALT_BUDGET 表示执行完了给定的周期数
+	HALT_BUDGET = "budget"
+)
+
+type CPU struct {
+	ROM [ROM_SIZE]uint16
+	RAM [RAM_SIZE]uint16
+	A   uint16
+	D   uint16
+	PC  uint16
+	// Cycles 是已执行的指令数
+	Cycles uint64
+	// Size 是装入的程序长度，PC 超出时报错
+	Size int
+	// writes 是改变了 RAM 内容的写入次数，loops 记录每个跳转目标上次跳到时的 A、D 和 writes，
+	// 再次跳到同一目标时三者都没变，说明整个状态在重复，程序不会再有变化
+	writes uint64
+	// loops 按跳转目标的地址索引，epoch 和 loopState.epoch 不同的记录已经作废，Resume 不用清空整个数组
+	loops  []loopState
+	epoch  uint32
+	halted bool
+}
+
+type loopState struct {
+	a, d   uint16
+	writes uint64
+	epoch  uint32
+}
+
+func NewCPU() *CPU {
+	c := &CPU{}
+	c.Reset()
+	return c
+}
+
+// Load 装入程序并复位，每个字都必须能按汇编器的对照表解码
+func (c *CPU) Load(words []uint16) error {
+	if len(words) > ROM_SIZE {
+		return fmt.Errorf("program has %d words but ROM holds only %d", len(words), ROM_SIZE)
+	}
+	disasm := asm.NewDisassembler()
+	for i, word := range words {
+		if _, err := disasm.Decode(word); err != nil {
+			return fmt.Errorf("address %d: %v", i, err)
+		}
+	}
+	c.ROM = [ROM_SIZE]uint16{}
+	copy(c.ROM[:], words)
+	c.Size = len(words)
+	c.Reset()
+	return nil
+}
+
+// Reset 把寄存器和周期数清零，RAM 保持不变，和 Hack 计算机的 reset 一样
+func (c *CPU) Reset() {
+	c.A, c.D, c.PC = 0, 0, 0
+	c.Cycles = 0
+	c.Resume()
+}
+
+// Resume 在从外部修改了寄存器之后调用，重新开始死循环检测
+func (c *CPU) Resume() {
+	if c.loops == nil {
+		c.loops = make([]loopState, ROM_SIZE)
+	}
+	c.epoch += 1
+	c.halted = false
+}
+
+// SetKey 设置当前按下的键，0 表示没有按键
+func (c *CPU) SetKey(key uint16) {
+	c.Poke(KBD, key)
+}
+
+// Read 读 RAM，地址只取低 15 位
+func (c *CPU) Read(address uint16) uint16 {
+	return c.RAM[address&ADDRESS_MASK]
+}
+
+// Write 写 RAM，键盘寄存器是只读的，写它不起作用
+func (c *CPU) Write(address uint16, value uint16) {
+	address &= ADDRESS_MASK
+	if address == KBD || c.RAM[address] == value {
+		return
+	}
+	c.writes += 1
+	c.RAM[address] = value
+}
+
+// Poke 从外部修改 RAM（包括键盘寄存器），之后的死循环检测重新开始
+func (c *CPU) Poke(address uint16, value uint16) {
+	address &= ADDRESS_MASK
+	if c.RAM[address] != value {
+		c.writes += 1
+		c.halted = false
+	}
+	c.RAM[address] = value
+}
+
+// Step 执行 PC 处的一条指令。M 的地址和跳转目标都用执行前的 A，和硬件在同一个时钟沿更新寄存器一致
+func (c *CPU) Step() error {
+	if int(c.PC) >= c.Size {
+		return c.pastEnd()
+	}
+	instruction := c.ROM[c.PC]
+	c.Cycles += 1
+	if instruction&0x8000 == 0 {
+		c.A = instruction
+		c.PC += 1
+		return nil
+	}
+
+	y := c.A
+	if instruction&BIT_A != 0 {
+		y = c.Read(c.A)
+	}
+	out := ALU(instruction, c.D, y)
+	address := c.A
+	if instruction&DEST_M != 0 {
+		c.Write(address, out)
+	}
+	if instruction&DEST_A != 0 {
+		c.A = out
+	}
+	if instruction&DEST_D != 0 {
+		c.D = out
+	}
+	if Jumps(instruction, out) {
+		c.PC = address & ADDRESS_MASK
+		c.checkLoop()
+	} else {
+		c.PC += 1
+	}
+	return nil
+}
+
+func (c *CPU) pastEnd() error {
+	return fmt.Errorf("PC %d is past the end of the program (%d words)", c.PC, c.Size)
+}
+
+// checkLoop 在跳转之后检查是否回到了和上次跳到这里时完全相同的状态
+func (c *CPU) checkLoop() {
+	state := loopState{a: c.A, d: c.D, writes: c.writes, epoch: c.epoch}
+	if c.loops[c.PC] == state {
+		c.halted = true
+	}
+	c.loops[c.PC] = state
+}
+
+// Halted 判断程序是否进入了死循环，如 (END) @END 0;JMP，或者反复执行不改变任何状态的一段代码
+func (c *CPU) Halted() bool {
+	return c.halted
+}
+
+// Run 执行最多 budget 条指令，遇到死循环时提前结束，返回结束的原因
+func (c *CPU) Run(budget uint64) (string, error) {
+	for i := uint64(0); i < budget && !c.halted; i++ {
+		if err := c.Step(); err != nil {
+			return "", err
+		}
+	}
+	if c.halted {
+		return HALT_LOOP, nil
+	}
+	return HALT_BUDGET, nil
+}
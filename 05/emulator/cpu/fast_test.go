@@ -0,0 +1,148 @@
+package cpu
+
+import (
+	"path/filepath"
+	"testing"
+
+	"hongkuancn/nand2tetris/assembler/asm"
+)
+
+// PONG 是 06 中课程提供的 Pong 程序，由 Jack 编译而来，包含 OS
+var PONG = filepath.Join("..", "..", "..", "06", "pong", "Pong.asm")
+
+// sameState 比较两个引擎的寄存器、周期数和死循环检测，full 时还比较整个 RAM
+func sameState(t *testing.T, name string, ref *CPU, fast *Fast, full bool) {
+	t.Helper()
+	if ref.A != fast.A || ref.D != fast.D || ref.PC != fast.PC || ref.Cycles != fast.Cycles || ref.Halted() != fast.Halted() {
+		t.Fatalf("%s: reference A=%d D=%d PC=%d cycles=%d halted=%v, fast A=%d D=%d PC=%d cycles=%d halted=%v", name,
+			ref.A, ref.D, ref.PC, ref.Cycles, ref.Halted(), fast.A, fast.D, fast.PC, fast.Cycles, fast.Halted())
+	}
+	if full && ref.RAM != fast.RAM {
+		for a := range ref.RAM {
+			if ref.RAM[a] != fast.RAM[a] {
+				t.Fatalf("%s: RAM[%d] is %d, fast %d", name, a, ref.RAM[a], fast.RAM[a])
+			}
+		}
+	}
+}
+
+// TestFastInstructions 每种合法的 comp、dest、jump 组合在不同的 A、D、M 上单步执行，结果和 CPU.Step 相同
+func TestFastInstructions(t *testing.T) {
+	d := asm.NewDisassembler()
+	values := []uint16{0, 1, 2, 0x7fff, 0x8000, 0xffff, 12345}
+	ref := NewCPU()
+	fast := NewFast(NewCPU())
+	instructions := 0
+	for comp := uint16(0); comp < 128; comp++ {
+		for dest := uint16(0); dest < 8; dest++ {
+			for jump := uint16(0); jump < 8; jump++ {
+				instruction := 0xe000 | comp<<6 | dest<<3 | jump
+				word, err := d.Decode(instruction)
+				if err != nil {
+					continue
+				}
+				instructions += 1
+				name := word.String()
+				if err := ref.Load([]uint16{instruction}); err != nil {
+					t.Fatal(err)
+				}
+				if err := fast.Load([]uint16{instruction}); err != nil {
+					t.Fatal(err)
+				}
+				for _, a := range values {
+					for _, x := range values {
+						for _, m := range values {
+							for _, c := range []*CPU{ref, fast.CPU} {
+								c.A, c.D, c.PC = a, x, 0
+								c.RAM[a&ADDRESS_MASK] = m
+								c.Resume()
+							}
+							if err := ref.Step(); err != nil {
+								t.Fatalf("%s: %v", name, err)
+							}
+							if err := fast.Step(); err != nil {
+								t.Fatalf("%s: fast: %v", name, err)
+							}
+							sameState(t, name, ref, fast, false)
+							if ref.RAM[a&ADDRESS_MASK] != fast.RAM[a&ADDRESS_MASK] {
+								t.Fatalf("%s with A=%d D=%d M=%d: M is %d, fast %d", name, a, x, m, ref.RAM[a&ADDRESS_MASK], fast.RAM[a&ADDRESS_MASK])
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	// 28 种 comp，8 种 dest，8 种 jump
+	if instructions != 28*8*8 {
+		t.Fatalf("checked %d instructions, want %d", instructions, 28*8*8)
+	}
+}
+
+// TestFastPong 两个引擎同步执行 Pong，每一步比较寄存器，定期比较 RAM；中途按住左键移动球拍
+func TestFastPong(t *testing.T) {
+	words, err := ReadProgram(PONG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := NewCPU()
+	c := NewCPU()
+	for _, cpu := range []*CPU{ref, c} {
+		if err := cpu.Load(words); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fast := NewFast(c)
+	for i := 0; i < 3000000 && !ref.Halted(); i++ {
+		if i == 1000000 || i == 1500000 {
+			key := uint16(130)
+			if i == 1500000 {
+				key = 0
+			}
+			ref.SetKey(key)
+			fast.SetKey(key)
+		}
+		if err := ref.Step(); err != nil {
+			t.Fatal(err)
+		}
+		if err := fast.Step(); err != nil {
+			t.Fatal(err)
+		}
+		sameState(t, "Pong", ref, fast, i%100000 == 0)
+	}
+	sameState(t, "Pong", ref, fast, true)
+}
+
+// benchmarkPong 用 run 执行 Pong 共 b.N 条指令，程序结束时重新装入，报告每秒执行的指令数
+func benchmarkPong(b *testing.B, engine func(c *CPU) func(budget uint64) (string, error)) {
+	words, err := ReadProgram(PONG)
+	if err != nil {
+		b.Fatal(err)
+	}
+	c := NewCPU()
+	if err := c.Load(words); err != nil {
+		b.Fatal(err)
+	}
+	run := engine(c)
+	b.ResetTimer()
+	for done := uint64(0); done < uint64(b.N); {
+		start := c.Cycles
+		reason, err := run(uint64(b.N) - done)
+		if err != nil {
+			b.Fatal(err)
+		}
+		done += c.Cycles - start
+		if reason == HALT_LOOP {
+			c.Reset()
+		}
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds()/1e6, "Minstr/s")
+}
+
+func BenchmarkStep(b *testing.B) {
+	benchmarkPong(b, func(c *CPU) func(budget uint64) (string, error) { return c.Run })
+}
+
+func BenchmarkFast(b *testing.B) {
+	benchmarkPong(b, func(c *CPU) func(budget uint64) (string, error) { return NewFast(c).Run })
+}
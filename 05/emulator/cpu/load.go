@@ -0,0 +1,54 @@
+package cpu
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"hongkuancn/nand2tetris/assembler/asm"
+)
+
+// ReadProgram 读取 .hack 程序。.asm 文件先用 06/assembler 汇编，有错误时返回所有诊断信息
+func ReadProgram(file string) ([]uint16, error) {
+	src, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	if strings.ToLower(filepath.Ext(file)) != ".asm" {
+		words, err := asm.ReadHack(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		return words, nil
+	}
+
+	prog, err := assemble(src, file)
+	if err != nil {
+		return nil, err
+	}
+	return prog.Words(), nil
+}
+
+// Assemble 汇编 .asm 文件，返回的 Program 带有符号表和源码行，供调试器使用
+func Assemble(file string) (*asm.Program, error) {
+	src, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	return assemble(src, file)
+}
+
+func assemble(src io.Reader, file string) (*asm.Program, error) {
+	opts := asm.Options{Name: file, Open: func(name string) (io.ReadCloser, error) { return os.Open(name) }, NoWarn: map[string]bool{}}
+	prog, diags := asm.Assemble(src, opts)
+	if diags.HasErrors() {
+		builder := strings.Builder{}
+		diags.Print(&builder)
+		return nil, fmt.Errorf("%s", strings.TrimSpace(builder.String()))
+	}
+	return prog, nil
+}
@@ -0,0 +1,589 @@
+// Package debug 是 Hack 程序的命令行调试器：单步、断点、观察点，以及寄存器和内存的查看。
+// 程序来自 .asm 时可以用符号表中的名字，并在指令旁显示对应的源码行。
+// 设置了 Trace 时不执行程序，而是重放录下的 trace，可以向后单步和向后继续
+package debug
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"hongkuancn/nand2tetris/assembler/asm"
+	"hongkuancn/nand2tetris/emulator/cpu"
+	"hongkuancn/nand2tetris/emulator/screen"
+	"hongkuancn/nand2tetris/emulator/trace"
+)
+
+// MAX_CYCLES 是 continue 和 next 默认最多执行的指令数，防止程序不停止时调试器卡住
+const MAX_CYCLES = 10000000
+
+const HELP = `commands:
+  step [n]           execute n instructions (s)
+  next               execute until the next source line, running over jumps and calls (n)
+  continue           run until a breakpoint, a watchpoint, or the program halts (c)
+  back [n]           step n instructions backwards when replaying a trace (bs)
+  rcontinue          run backwards to a breakpoint, a watchpoint, or the start of the trace (rc)
+  break <addr|label> stop before executing the instruction (b)
+  watch <addr|name>  stop when the RAM word changes (w)
+  delete <addr|name> remove a breakpoint or watchpoint (d)
+  info               list breakpoints and watchpoints
+  regs               show A, D, M, PC and the cycle count (r)
+  x <addr|name> [n]  show n RAM words
+  list [n]           show the source around PC (l)
+  png <file>         save the screen to a PNG file
+  reset              reset the registers, keeping RAM; go to the start of a trace
+  help               show this help
+  quit               leave the debugger (q)
+an empty line repeats the last command`
+
+// location 是一个 ROM 地址对应的源码
+type location struct {
+	pos  asm.Pos
+	line string
+	// last 是同一源码行（宏展开）的最后一条指令的地址
+	last int
+}
+
+type Debugger struct {
+	CPU *cpu.CPU
+	Out io.Writer
+	// MaxCycles 限制一次 continue 或 next 执行的指令数，为 0 时是 MAX_CYCLES
+	MaxCycles uint64
+	// Trace 不为 nil 时重放 trace，CPU 的状态全部来自 trace
+	Trace *trace.Replay
+
+	symbols     *asm.SymbolTable
+	labels      map[int][]string
+	locations   map[int]location
+	files       map[string][]string
+	disasm      *asm.Disassembler
+	breakpoints map[uint16]bool
+	// watchpoints 记录每个观察的地址上次看到的值
+	watchpoints map[uint16]uint16
+	last        string
+}
+
+// New 创建调试器。prog 是 c 中程序的汇编结果，为 nil（.hack 文件）时只能用数字地址和预定义符号
+func New(c *cpu.CPU, prog *asm.Program, out io.Writer) *Debugger {
+	d := &Debugger{
+		CPU:         c,
+		Out:         out,
+		labels:      make(map[int][]string),
+		locations:   make(map[int]location),
+		files:       make(map[string][]string),
+		disasm:      asm.NewDisassembler(),
+		breakpoints: make(map[uint16]bool),
+		watchpoints: make(map[uint16]uint16),
+	}
+	if prog == nil {
+		d.symbols = asm.NewSymbolTable()
+		d.symbols.Init()
+		return d
+	}
+	d.symbols = prog.Symbols
+	for _, file := range prog.Files {
+		d.files[file.Name] = file.Lines
+	}
+	for _, inst := range prog.Instructions {
+		if inst.Type == asm.L_INSTRUCTION {
+			continue
+		}
+		loc, ok := d.locations[inst.Address]
+		if !ok {
+			loc = location{pos: inst.Pos, line: d.sourceLine(inst.Pos)}
+		}
+		loc.last = inst.Address
+		d.locations[inst.Address] = loc
+		// 宏展开出的指令都对应调用宏的那一行
+		for a := inst.Address - 1; a >= 0; a-- {
+			prev, ok := d.locations[a]
+			if !ok || prev.pos != inst.Pos {
+				break
+			}
+			prev.last = inst.Address
+			d.locations[a] = prev
+		}
+	}
+	for _, sym := range prog.Symbols.Symbols() {
+		if sym.Kind == asm.SYM_LABEL {
+			d.labels[sym.Address] = append(d.labels[sym.Address], sym.Name)
+		}
+	}
+	return d
+}
+
+func (d *Debugger) sourceLine(pos asm.Pos) string {
+	lines := d.files[pos.File]
+	if pos.Line < 1 || pos.Line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(lines[pos.Line-1], "\r"))
+}
+
+func (d *Debugger) printf(format string, args ...any) {
+	fmt.Fprintf(d.Out, format, args...)
+}
+
+// Run 从 in 读取命令并执行，直到 quit 或输入结束
+func (d *Debugger) Run(in io.Reader) error {
+	d.Where()
+	scanner := bufio.NewScanner(in)
+	for {
+		d.printf("(hdb) ")
+		if !scanner.Scan() {
+			d.printf("\n")
+			return scanner.Err()
+		}
+		quit, err := d.Exec(scanner.Text())
+		if err != nil {
+			d.printf("error: %v\n", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// Exec 执行一条调试命令，quit 为 true 表示要退出调试器
+func (d *Debugger) Exec(line string) (quit bool, err error) {
+	if strings.TrimSpace(line) == "" {
+		line = d.last
+	}
+	d.last = line
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	args := fields[1:]
+	switch fields[0] {
+	case "step", "s":
+		n := 1
+		if len(args) > 0 {
+			n, err = strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return false, fmt.Errorf("invalid step count %q", args[0])
+			}
+		}
+		return false, d.Step(n)
+	case "next", "n":
+		return false, d.Next()
+	case "continue", "c":
+		return false, d.Continue()
+	case "back", "bs":
+		n := 1
+		if len(args) > 0 {
+			n, err = strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return false, fmt.Errorf("invalid step count %q", args[0])
+			}
+		}
+		return false, d.Back(n)
+	case "rcontinue", "rc":
+		return false, d.ReverseContinue()
+	case "break", "b":
+		return false, d.Break(args)
+	case "watch", "w":
+		return false, d.Watch(args)
+	case "delete", "d":
+		return false, d.Delete(args)
+	case "info", "i":
+		d.Info()
+	case "regs", "r":
+		d.Regs()
+	case "x":
+		return false, d.Examine(args)
+	case "list", "l":
+		n := 5
+		if len(args) > 0 {
+			n, err = strconv.Atoi(args[0])
+			if err != nil || n < 0 {
+				return false, fmt.Errorf("invalid line count %q", args[0])
+			}
+		}
+		d.List(n)
+	case "png":
+		if len(args) != 1 {
+			return false, fmt.Errorf("usage: png <file>")
+		}
+		if err := screen.SavePNG(args[0], &d.CPU.RAM); err != nil {
+			return false, err
+		}
+		d.printf("saved the screen to %s\n", args[0])
+	case "reset":
+		if d.Trace != nil {
+			for d.Trace.Back() {
+			}
+			d.syncWatches()
+		} else {
+			d.CPU.Reset()
+		}
+		d.Where()
+	case "help", "h", "?":
+		d.printf("%s\n", HELP)
+	case "quit", "q", "exit":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command %q, try help", fields[0])
+	}
+	return false, nil
+}
+
+// Step 执行 n 条指令，观察点变化或程序停止时提前结束
+func (d *Debugger) Step(n int) error {
+	for i := 0; i < n; i++ {
+		stopped, err := d.step()
+		if err != nil || stopped {
+			d.Where()
+			return err
+		}
+	}
+	d.Where()
+	return nil
+}
+
+// Next 执行到当前源码行之后的那条指令，中间的跳转和子程序调用一并执行完。
+// 执行过程中遇到断点、观察点或程序停止时提前结束
+func (d *Debugger) Next() error {
+	target := d.CPU.PC + 1
+	if loc, ok := d.locations[int(d.CPU.PC)]; ok {
+		target = uint16(loc.last + 1)
+	}
+	return d.runUntil(func() bool { return d.CPU.PC == target })
+}
+
+// Continue 一直执行到断点、观察点或程序停止
+func (d *Debugger) Continue() error {
+	return d.runUntil(func() bool { return false })
+}
+
+// runUntil 至少执行一条指令，使得从断点处继续时不会马上停下
+func (d *Debugger) runUntil(done func() bool) error {
+	limit := d.MaxCycles
+	if limit == 0 {
+		limit = MAX_CYCLES
+	}
+	for i := uint64(0); ; i++ {
+		if i == limit {
+			d.printf("stopped after %d cycles\n", limit)
+			break
+		}
+		stopped, err := d.step()
+		if err != nil {
+			d.Where()
+			return err
+		}
+		if stopped || done() {
+			break
+		}
+		if d.breakpoints[d.CPU.PC] {
+			d.printf("breakpoint at %s\n", d.addressName(d.CPU.PC))
+			break
+		}
+	}
+	d.Where()
+	return nil
+}
+
+// step 执行一条指令，观察点变化或程序停止时返回 true
+func (d *Debugger) step() (bool, error) {
+	if d.Trace != nil {
+		if !d.Trace.Step() {
+			d.printf("end of trace after %d cycles\n", d.CPU.Cycles)
+			return true, nil
+		}
+		return d.checkWatches(), nil
+	}
+	if d.CPU.Halted() {
+		d.printf("program has halted\n")
+		return true, nil
+	}
+	if err := d.CPU.Step(); err != nil {
+		return true, err
+	}
+	stopped := d.checkWatches()
+	if d.CPU.Halted() {
+		d.printf("program halted after %d cycles\n", d.CPU.Cycles)
+		stopped = true
+	}
+	return stopped, nil
+}
+
+// checkWatches 输出值改变了的观察点，有改变时返回 true
+func (d *Debugger) checkWatches() bool {
+	changed := false
+	for _, address := range d.watched() {
+		value := d.CPU.RAM[address]
+		if old := d.watchpoints[address]; old != value {
+			d.printf("watch %s: %d -> %d\n", d.ramName(address), int16(old), int16(value))
+			d.watchpoints[address] = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+// syncWatches 在跳过一段 trace 后更新观察点记住的值，不算作改变
+func (d *Debugger) syncWatches() {
+	for address := range d.watchpoints {
+		d.watchpoints[address] = d.CPU.RAM[address]
+	}
+}
+
+// Back 在 trace 中向后退 n 条指令，观察点变化或到了 trace 的开头时提前结束
+func (d *Debugger) Back(n int) error {
+	if d.Trace == nil {
+		return fmt.Errorf("reverse execution needs a trace, run the emulator with -replay")
+	}
+	for i := 0; i < n; i++ {
+		if d.back() {
+			break
+		}
+	}
+	d.Where()
+	return nil
+}
+
+// ReverseContinue 在 trace 中一直向后退，直到断点、观察点或 trace 的开头。
+// 停在断点时 PC 指向断点处的指令，即将执行它
+func (d *Debugger) ReverseContinue() error {
+	if d.Trace == nil {
+		return fmt.Errorf("reverse execution needs a trace, run the emulator with -replay")
+	}
+	limit := d.MaxCycles
+	if limit == 0 {
+		limit = MAX_CYCLES
+	}
+	for i := uint64(0); ; i++ {
+		if i == limit {
+			d.printf("stopped after %d cycles\n", limit)
+			break
+		}
+		if d.back() {
+			break
+		}
+		if d.breakpoints[d.CPU.PC] {
+			d.printf("breakpoint at %s\n", d.addressName(d.CPU.PC))
+			break
+		}
+	}
+	d.Where()
+	return nil
+}
+
+// back 向后退一条指令，观察点变化或到了 trace 的开头时返回 true
+func (d *Debugger) back() bool {
+	if !d.Trace.Back() {
+		d.printf("start of trace at cycle %d\n", d.CPU.Cycles)
+		return true
+	}
+	return d.checkWatches()
+}
+
+func (d *Debugger) watched() []uint16 {
+	addresses := make([]uint16, 0, len(d.watchpoints))
+	for address := range d.watchpoints {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+	return addresses
+}
+
+// Break 在 ROM 地址或 label 处设置断点
+func (d *Debugger) Break(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: break <address|label>")
+	}
+	address, err := d.romAddress(args[0])
+	if err != nil {
+		return err
+	}
+	d.breakpoints[address] = true
+	d.printf("breakpoint at %s\n", d.addressName(address))
+	return nil
+}
+
+// Watch 观察 RAM 地址或符号（如 SP、LCL、变量），值改变时停下
+func (d *Debugger) Watch(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: watch <address|symbol>")
+	}
+	address, err := d.ramAddress(args[0])
+	if err != nil {
+		return err
+	}
+	d.watchpoints[address] = d.CPU.RAM[address]
+	d.printf("watching %s = %d\n", d.ramName(address), int16(d.CPU.RAM[address]))
+	return nil
+}
+
+// Delete 删除断点或观察点。参数是 label 时删除断点，是预定义符号或变量时删除观察点，是数字时两者都删
+func (d *Debugger) Delete(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: delete <address|name>")
+	}
+	deleted := false
+	if address, err := d.romAddress(args[0]); err == nil && d.breakpoints[address] {
+		delete(d.breakpoints, address)
+		deleted = true
+	}
+	if address, err := d.ramAddress(args[0]); err == nil {
+		if _, ok := d.watchpoints[address]; ok {
+			delete(d.watchpoints, address)
+			deleted = true
+		}
+	}
+	if !deleted {
+		return fmt.Errorf("no breakpoint or watchpoint at %s", args[0])
+	}
+	d.printf("deleted %s\n", args[0])
+	return nil
+}
+
+// Info 列出所有断点和观察点
+func (d *Debugger) Info() {
+	addresses := make([]uint16, 0, len(d.breakpoints))
+	for address := range d.breakpoints {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+	for _, address := range addresses {
+		d.printf("breakpoint %s\n", d.addressName(address))
+	}
+	for _, address := range d.watched() {
+		d.printf("watchpoint %s = %d\n", d.ramName(address), int16(d.CPU.RAM[address]))
+	}
+	if len(addresses) == 0 && len(d.watchpoints) == 0 {
+		d.printf("no breakpoints or watchpoints\n")
+	}
+}
+
+// Regs 输出寄存器，M 是 RAM[A]
+func (d *Debugger) Regs() {
+	c := d.CPU
+	d.printf("A=%d D=%d M=%d PC=%d cycles=%d\n", int16(c.A), int16(c.D), int16(c.Read(c.A)), c.PC, c.Cycles)
+}
+
+// Examine 输出从某个地址开始的 n 个 RAM 字
+func (d *Debugger) Examine(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: x <address|symbol> [count]")
+	}
+	address, err := d.ramAddress(args[0])
+	if err != nil {
+		return err
+	}
+	n := 1
+	if len(args) == 2 {
+		n, err = strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid count %q", args[1])
+		}
+	}
+	for a := int(address); a < int(address)+n && a < cpu.RAM_SIZE; a++ {
+		d.printf("%-12s %6d  %016b\n", d.ramName(uint16(a)), int16(d.CPU.RAM[a]), d.CPU.RAM[a])
+	}
+	return nil
+}
+
+// List 输出 PC 前后各 n 条指令
+func (d *Debugger) List(n int) {
+	pc := int(d.CPU.PC)
+	for a := pc - n; a <= pc+n; a++ {
+		if a < 0 || a >= d.CPU.Size {
+			continue
+		}
+		marker := "  "
+		if a == pc {
+			marker = "=>"
+		}
+		d.printf("%s %s\n", marker, d.describe(a))
+	}
+}
+
+// Where 输出 PC 处的指令：地址、label、反汇编的指令和源码行
+func (d *Debugger) Where() {
+	if int(d.CPU.PC) >= d.CPU.Size {
+		d.printf("PC %d is past the end of the program\n", d.CPU.PC)
+		return
+	}
+	d.printf("=> %s\n", d.describe(int(d.CPU.PC)))
+}
+
+func (d *Debugger) describe(address int) string {
+	text := "?"
+	if word, err := d.disasm.Decode(d.CPU.ROM[address]); err == nil {
+		text = word.String()
+	}
+	mark := " "
+	if d.breakpoints[uint16(address)] {
+		mark = "*"
+	}
+	line := fmt.Sprintf("%05d%s %-14s", address, mark, text)
+	if names := d.labels[address]; len(names) > 0 {
+		line += " (" + strings.Join(names, ", ") + ")"
+	}
+	if loc, ok := d.locations[address]; ok {
+		line += fmt.Sprintf("  %s:%d  %s", loc.pos.File, loc.pos.Line, loc.line)
+	}
+	return strings.TrimRight(line, " ")
+}
+
+// addressName 返回 ROM 地址，有 label 时带上名字
+func (d *Debugger) addressName(address uint16) string {
+	if names := d.labels[int(address)]; len(names) > 0 {
+		return fmt.Sprintf("%d (%s)", address, names[0])
+	}
+	return strconv.Itoa(int(address))
+}
+
+// ramName 返回 RAM 地址，是预定义符号或变量时带上名字
+func (d *Debugger) ramName(address uint16) string {
+	name := fmt.Sprintf("RAM[%d]", address)
+	for _, sym := range d.symbols.Symbols() {
+		if sym.Address == int(address) && sym.Kind != asm.SYM_LABEL && !isRegister(sym.Name) {
+			return name + " " + sym.Name
+		}
+	}
+	return name
+}
+
+// isRegister 判断是否是 R0-R15，这些名字不如 SP、LCL 或变量名有用
+func isRegister(name string) bool {
+	n, err := strconv.Atoi(strings.TrimPrefix(name, "R"))
+	return err == nil && name[0] == 'R' && n >= 0 && n <= 15
+}
+
+// romAddress 解析断点位置：数字或 label
+func (d *Debugger) romAddress(text string) (uint16, error) {
+	if n, err := strconv.Atoi(text); err == nil {
+		if n < 0 || n >= d.CPU.Size {
+			return 0, fmt.Errorf("ROM address %d is outside the program (%d words)", n, d.CPU.Size)
+		}
+		return uint16(n), nil
+	}
+	if !d.symbols.Contains(text) || d.symbols.Kind(text) != asm.SYM_LABEL {
+		return 0, fmt.Errorf("unknown label %q", text)
+	}
+	return uint16(d.symbols.GetAddress(text)), nil
+}
+
+// ramAddress 解析观察点和 x 的地址：数字、RAM[n]、预定义符号或变量
+func (d *Debugger) ramAddress(text string) (uint16, error) {
+	inner := text
+	if strings.HasPrefix(text, "RAM[") && strings.HasSuffix(text, "]") {
+		inner = text[4 : len(text)-1]
+	}
+	if n, err := strconv.Atoi(inner); err == nil {
+		if n < 0 || n >= cpu.RAM_SIZE {
+			return 0, fmt.Errorf("RAM address %d is out of range", n)
+		}
+		return uint16(n), nil
+	}
+	if !d.symbols.Contains(text) || d.symbols.Kind(text) == asm.SYM_LABEL {
+		return 0, fmt.Errorf("unknown RAM symbol %q", text)
+	}
+	return uint16(d.symbols.GetAddress(text)), nil
+}
@@ -0,0 +1,7 @@
+module hongkuancn/nand2tetris/emulator
+
+go 1.20
+
+require hongkuancn/nand2tetris/assembler v0.0.0
+
+replace hongkuancn/nand2tetris/assembler => ../../06/assembler
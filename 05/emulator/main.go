@@ -0,0 +1,540 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"hongkuancn/nand2tetris/assembler/asm"
+	"hongkuancn/nand2tetris/emulator/cpu"
+	"hongkuancn/nand2tetris/emulator/debug"
+	"hongkuancn/nand2tetris/emulator/profile"
+	"hongkuancn/nand2tetris/emulator/screen"
+	"hongkuancn/nand2tetris/emulator/trace"
+	"hongkuancn/nand2tetris/emulator/tst"
+	"hongkuancn/nand2tetris/emulator/vm"
+)
+
+func main() {
+	cycles := flag.Uint64("cycles", 10000000, "stop after `n` instructions if the program has not halted")
+	set := flag.String("set", "", "initialize RAM before running, e.g. `0=3,1=5`")
+	dump := flag.String("ram", "0-15", "RAM `ranges` to print after running, e.g. 0-15,256")
+	bootstrap := flag.Bool("bootstrap", true, "for VM programs with Sys.init, set SP=256 and call Sys.init first")
+	debugger := flag.Bool("debug", false, "debug the hack or asm program interactively")
+	mode := flag.String("screen", "", "show the screen in the terminal and send keystrokes to the keyboard, `mode` is braille or half")
+	scale := flag.Int("scale", 2, "shrink the terminal screen by `n` times")
+	speed := flag.Uint64("speed", 2000000, "run `n` instructions (or VM commands) per second with -screen")
+	pngFile := flag.String("png", "", "save the screen to a PNG `file` when the program halts")
+	pngAt := flag.Uint64("png-at", 0, "take the -png snapshot after `n` cycles instead")
+	keys := flag.String("keys", "", "drive the keyboard from a keystroke script `file`")
+	profiling := flag.Bool("profile", false, "report the cycles spent in each function and label of a hack or asm program")
+	folded := flag.String("folded", "", "write the profiled call stacks to `file` in the folded format for flame graphs")
+	fast := flag.Bool("fast", false, "run hack or asm programs with the pre-decoded engine")
+	bench := flag.Bool("bench", false, "run a hack or asm program with both engines, compare their speed and check the results are identical")
+	expect := flag.String("expect", "", "fail unless RAM holds these values at the end, e.g. `0=3,1=5`")
+	reference := flag.String("compare", "", "compare the screen with a PNG or GIF `image`, a 512x256 snapshot or a scaled-down emulator screenshot such as 12/OutputTest/OutputTestOutput.gif")
+	tolerance := flag.Float64("tolerance", 0, "`percent` of pixels that may differ with -compare (default 0 for 512x256 snapshots, 1 for scaled screenshots)")
+	record := flag.String("record", "", "record the PC, A, D and RAM writes of every cycle of a hack or asm program to a trace `file`")
+	replay := flag.String("replay", "", "debug a recorded trace `file` of the program, starting at its end and able to step backwards")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Println("usage: emulator [-cycles n] [-set addr=value,...] [-ram ranges] <hack or asm file>")
+		fmt.Println("       emulator -debug [-cycles n] [-set addr=value,...] <hack or asm file>")
+		fmt.Println("       emulator -screen braille|half [-scale n] [-speed n] [-set addr=value,...] <hack, asm or vm program>")
+		fmt.Println("       emulator [-cycles n] [-png file] [-png-at n] [-compare image] [-tolerance percent] <hack, asm or vm program>")
+		fmt.Println("       emulator [-cycles n] -keys script [-expect addr=value,...] [-png file] [-compare image] <hack, asm or vm program>")
+		fmt.Println("       emulator [-cycles n] -profile [-folded file] <hack or asm file>")
+		fmt.Println("       emulator [-cycles n] -record trace [-keys script] [-screen mode] <hack or asm file>")
+		fmt.Println("       emulator -replay trace <hack or asm file>")
+		fmt.Println("       emulator [-cycles n] -bench [-set addr=value,...] [-keys script] <hack or asm file>")
+		fmt.Println("       emulator [-cycles n] [-bootstrap=false] [-set addr=value,...] [-ram ranges] <vm file or directory>")
+		fmt.Println("       emulator [-cycles n] <tst file>...")
+		os.Exit(1)
+	}
+	if strings.HasSuffix(flag.Arg(0), ".tst") {
+		if !runScripts(flag.Args(), *cycles) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	opts := runOptions{
+		cycles:    *cycles,
+		bootstrap: *bootstrap,
+		set:       *set,
+		dump:      *dump,
+		screen:    screen.Options{Mode: *mode, Scale: *scale, Speed: *speed},
+		png:       *pngFile,
+		pngAt:     *pngAt,
+		compare:   *reference,
+		expect:    *expect,
+		profile:   *profiling,
+		folded:    *folded,
+		fast:      *fast,
+		record:    *record,
+		replay:    *replay,
+	}
+	// 没有给出 -tolerance 时按参考图像的种类取默认值
+	opts.tolerance = -1
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "tolerance" {
+			opts.tolerance = *tolerance
+		}
+	})
+	var err error
+	if *keys != "" {
+		opts.keys, err = readKeys(*keys)
+	}
+	switch {
+	case err != nil:
+	case *bench:
+		err = runBench(flag.Arg(0), opts)
+	case *debugger || *replay != "":
+		err = runDebugger(flag.Arg(0), opts)
+	case isVM(flag.Arg(0)):
+		err = runVM(flag.Arg(0), opts)
+	default:
+		err = runCPU(flag.Arg(0), opts)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// runOptions 是执行程序的命令行参数
+type runOptions struct {
+	cycles    uint64
+	bootstrap bool
+	set       string
+	dump      string
+	// screen.Mode 不为空时在终端中显示屏幕
+	screen screen.Options
+	// png 和 compare 在程序结束或执行了 pngAt 个周期时保存屏幕截图、和参考图像比较
+	png     string
+	pngAt   uint64
+	compare string
+	// tolerance 是允许不同的像素的百分比，小于 0 时按参考图像的种类取默认值
+	tolerance float64
+	// keys 是按键脚本，expect 是结束时 RAM 中应有的值
+	keys   screen.KeyScript
+	expect string
+	// profile 输出每个函数和 label 的周期数，folded 是火焰图文件
+	profile bool
+	folded  string
+	// fast 用预解码的引擎执行
+	fast bool
+	// record 是录制 trace 的文件，replay 是调试器重放的 trace 文件
+	record string
+	replay string
+}
+
+// PROFILE_LABELS 是 profiler 报告中最多列出的 label 数
+const PROFILE_LABELS = 30
+
+// isVM 判断是否是 .vm 文件或者目录
+func isVM(file string) bool {
+	info, err := os.Stat(file)
+	return err == nil && info.IsDir() || strings.HasSuffix(file, ".vm")
+}
+
+// loadCPU 装入 .hack 或 .asm 程序并按 -set 初始化 RAM。.asm 同时返回汇编结果，供调试器和 profiler 使用符号
+func loadCPU(file string, opts runOptions) (*cpu.CPU, *asm.Program, error) {
+	var prog *asm.Program
+	var words []uint16
+	var err error
+	if strings.ToLower(filepath.Ext(file)) == ".asm" {
+		prog, err = cpu.Assemble(file)
+		if err == nil {
+			words = prog.Words()
+		}
+	} else {
+		words, err = cpu.ReadProgram(file)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	c := cpu.NewCPU()
+	err = c.Load(words)
+	if err == nil {
+		err = initRAM(func(a int, v uint16) { c.Poke(uint16(a), v) }, opts.set)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, prog, nil
+}
+
+// runCPU 用 CPU 模拟器执行 .hack 或 .asm 程序
+func runCPU(file string, opts runOptions) (err error) {
+	c, prog, err := loadCPU(file, opts)
+	if err != nil {
+		return err
+	}
+	var m screen.Machine = c
+	var profiler *profile.Profiler
+	if opts.fast {
+		m = cpu.NewFast(c)
+	}
+	if opts.profile || opts.folded != "" {
+		// profiler 自己用参考解释器单步执行
+		if opts.fast {
+			return fmt.Errorf("-fast cannot be combined with -profile or -folded")
+		}
+		profiler = profile.New(c, prog)
+		m = profiler
+	}
+	if opts.record != "" {
+		if profiler != nil || opts.fast {
+			return fmt.Errorf("-record cannot be combined with -profile or -fast")
+		}
+		recorder, finish, err := startTrace(c, opts.record)
+		if err != nil {
+			return err
+		}
+		m = recorder
+		// 出错时也写完 trace，这样可以从出错的地方向后查看
+		defer func() {
+			if ferr := finish(); err == nil {
+				err = ferr
+			}
+		}()
+	}
+	if opts.screen.Mode != "" {
+		err = play(m, &c.RAM, opts)
+	} else {
+		var reason string
+		reason, err = run(m, &c.RAM, opts)
+		if err != nil {
+			return fmt.Errorf("error after %d cycles: %v", c.Cycles, err)
+		}
+		fmt.Printf("%s after %d cycles: PC=%d A=%d D=%d\n", reason, c.Cycles, c.PC, int16(c.A), int16(c.D))
+		if err = dumpRAM(&c.RAM, opts.dump); err == nil {
+			err = checkRAM(&c.RAM, opts.expect)
+		}
+	}
+	if profiler != nil {
+		if perr := writeProfile(profiler, opts); err == nil {
+			err = perr
+		}
+	}
+	return err
+}
+
+// startTrace 开始把 c 的执行录制到 file，finish 写完并关闭文件
+func startTrace(c *cpu.CPU, file string) (*trace.Recorder, func() error, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	recorder, err := trace.NewRecorder(c, f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	start := c.Cycles
+	finish := func() error {
+		err := recorder.Close()
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("recorded %d cycles to %s\n", c.Cycles-start, file)
+		return nil
+	}
+	return recorder, finish, nil
+}
+
+// runBench 分别用参考解释器和预解码引擎执行同一个程序，输出每秒执行的指令数，并检查两者的结果完全相同
+func runBench(file string, opts runOptions) error {
+	ref, _, err := loadCPU(file, opts)
+	if err != nil {
+		return err
+	}
+	c, _, err := loadCPU(file, opts)
+	if err != nil {
+		return err
+	}
+	engines := []struct {
+		name string
+		cpu  *cpu.CPU
+		m    screen.Machine
+	}{{"reference", ref, ref}, {"fast", c, cpu.NewFast(c)}}
+	for _, engine := range engines {
+		start := time.Now()
+		reason, err := scripted(engine.m, opts.keys).Run(opts.cycles)
+		if err != nil {
+			return fmt.Errorf("%s: error after %d cycles: %v", engine.name, engine.cpu.Cycles, err)
+		}
+		elapsed := time.Since(start)
+		fmt.Printf("%-10s %s after %d cycles in %v, %.1f million instructions per second\n",
+			engine.name, reason, engine.cpu.Cycles, elapsed.Round(time.Millisecond), float64(engine.cpu.Cycles)/elapsed.Seconds()/1e6)
+	}
+	if ref.A != c.A || ref.D != c.D || ref.PC != c.PC || ref.Cycles != c.Cycles || ref.Halted() != c.Halted() {
+		return fmt.Errorf("registers differ: reference A=%d D=%d PC=%d cycles=%d, fast A=%d D=%d PC=%d cycles=%d",
+			ref.A, ref.D, ref.PC, ref.Cycles, c.A, c.D, c.PC, c.Cycles)
+	}
+	for a := range ref.RAM {
+		if ref.RAM[a] != c.RAM[a] {
+			return fmt.Errorf("RAM[%d] differs: reference %d, fast %d", a, int16(ref.RAM[a]), int16(c.RAM[a]))
+		}
+	}
+	fmt.Println("results are identical")
+	return nil
+}
+
+// writeProfile 输出 profiler 的报告，-folded 给出文件时写入火焰图用的 folded stack
+func writeProfile(profiler *profile.Profiler, opts runOptions) error {
+	if opts.profile {
+		fmt.Println()
+		profiler.Report(os.Stdout, PROFILE_LABELS)
+	}
+	if opts.folded == "" {
+		return nil
+	}
+	f, err := os.Create(opts.folded)
+	if err != nil {
+		return err
+	}
+	if err := profiler.WriteFolded(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runDebugger 在调试器中执行 .hack 或 .asm 程序，.asm 可以使用符号并显示源码行
+func runDebugger(file string, opts runOptions) error {
+	c, prog, err := loadCPU(file, opts)
+	if err != nil {
+		return err
+	}
+	d := debug.New(c, prog, os.Stdout)
+	d.MaxCycles = opts.cycles
+	if opts.replay != "" {
+		// 从 trace 的末尾开始，通常要从出问题的地方向后查看
+		d.Trace, err = trace.Open(opts.replay, c)
+		if err != nil {
+			return err
+		}
+		for d.Trace.Step() {
+		}
+		fmt.Printf("replaying %d cycles from cycle %d, at the end of the trace; back and rcontinue go backwards, reset goes to the start\n",
+			d.Trace.Cycles, d.Trace.Start)
+	}
+	return d.Run(os.Stdin)
+}
+
+// runVM 用 VM 模拟器执行 .vm 文件或目录中的所有 .vm 文件，有 Sys.init 时默认先引导
+func runVM(file string, opts runOptions) error {
+	prog, err := vm.ReadProgram(file)
+	if err != nil {
+		return err
+	}
+	v := vm.New(prog)
+	if _, ok := prog.Functions["Sys.init"]; ok && opts.bootstrap {
+		err = v.Bootstrap()
+	}
+	if err == nil {
+		err = initRAM(func(a int, value uint16) { v.Poke(a, value) }, opts.set)
+	}
+	if err != nil {
+		return err
+	}
+	if opts.screen.Mode != "" {
+		return play(v, &v.RAM, opts)
+	}
+	reason, err := run(v, &v.RAM, opts)
+	if err != nil {
+		return fmt.Errorf("error after %d steps: %v", v.Steps, err)
+	}
+	where := "the end of the program"
+	if cmd, ok := v.Current(); ok {
+		where = fmt.Sprintf("%s.vm:%d %s", cmd.File, cmd.Line, cmd.Text)
+	}
+	if v.Function() != "" {
+		where = v.Function() + ", " + where
+	}
+	fmt.Printf("%s after %d steps at %s\n", reason, v.Steps, where)
+	if err := dumpRAM(&v.RAM, opts.dump); err != nil {
+		return err
+	}
+	return checkRAM(&v.RAM, opts.expect)
+}
+
+func readKeys(file string) (screen.KeyScript, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	script, err := screen.ParseKeyScript(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return script, nil
+}
+
+// scripted 在给出按键脚本时让脚本驱动键盘
+func scripted(m screen.Machine, script screen.KeyScript) screen.Machine {
+	if script == nil {
+		return m
+	}
+	return screen.NewScripted(m, script)
+}
+
+// play 在终端中显示屏幕并运行程序，按键脚本和终端的按键都会送入键盘
+func play(m screen.Machine, ram *[cpu.RAM_SIZE]uint16, opts runOptions) error {
+	return screen.Play(scripted(m, opts.keys), ram, opts.screen)
+}
+
+// run 执行程序，需要时在 opts.pngAt 个周期或者程序结束时保存屏幕截图并和参考图像比较
+func run(m screen.Machine, ram *[cpu.RAM_SIZE]uint16, opts runOptions) (string, error) {
+	m = scripted(m, opts.keys)
+	if opts.png == "" && opts.compare == "" {
+		return m.Run(opts.cycles)
+	}
+	budget := opts.cycles
+	if opts.pngAt > 0 && opts.pngAt < budget {
+		budget = opts.pngAt
+	}
+	reason, err := m.Run(budget)
+	if err != nil {
+		return "", err
+	}
+	if err := snapshot(ram, opts); err != nil {
+		return "", err
+	}
+	if reason == cpu.HALT_BUDGET && budget < opts.cycles {
+		return m.Run(opts.cycles - budget)
+	}
+	return reason, nil
+}
+
+func snapshot(ram *[cpu.RAM_SIZE]uint16, opts runOptions) error {
+	if opts.png != "" {
+		if err := screen.SavePNG(opts.png, ram); err != nil {
+			return err
+		}
+	}
+	if opts.compare == "" {
+		return nil
+	}
+	want, err := screen.LoadImage(opts.compare)
+	if err != nil {
+		return err
+	}
+	diff, err := screen.Compare(screen.Image(ram), want)
+	if err != nil {
+		return fmt.Errorf("%s: %v", opts.compare, err)
+	}
+	tolerance := opts.tolerance
+	if tolerance < 0 && diff.Scaled {
+		tolerance = screen.SCALED_TOLERANCE
+	}
+	if diff.Pixels > 0 && diff.Percent() > tolerance {
+		return fmt.Errorf("screen differs from %s: %v", opts.compare, diff)
+	}
+	if diff.Pixels > 0 {
+		fmt.Printf("screen matches %s within %.2f%%: %v\n", opts.compare, tolerance, diff)
+		return nil
+	}
+	fmt.Printf("screen matches %s\n", opts.compare)
+	return nil
+}
+
+// runScripts 依次执行测试脚本，输出每个脚本是否通过，全部通过时返回 true
+func runScripts(files []string, cycles uint64) bool {
+	runner := tst.NewRunner()
+	runner.MaxSteps = int(cycles)
+	runner.Echo = os.Stdout
+	passed := true
+	for _, file := range files {
+		err := runner.Run(file)
+		if err != nil {
+			fmt.Printf("FAIL %s\n%v\n", file, err)
+			passed = false
+			continue
+		}
+		fmt.Printf("ok   %s\n", file)
+	}
+	return passed
+}
+
+// initRAM 按 "地址=值" 的列表初始化 RAM
+func initRAM(poke func(address int, value uint16), set string) error {
+	for _, item := range strings.Split(set, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		address, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("invalid RAM setting %q, expected address=value", item)
+		}
+		a, err := parseAddress(address)
+		if err != nil {
+			return err
+		}
+		v, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil || v < -32768 || v > 65535 {
+			return fmt.Errorf("invalid RAM value %q", value)
+		}
+		poke(a, uint16(v))
+	}
+	return nil
+}
+
+// checkRAM 检查 "地址=值" 的列表中的 RAM 都符合预期
+func checkRAM(ram *[cpu.RAM_SIZE]uint16, expect string) error {
+	failures := make([]string, 0)
+	err := initRAM(func(a int, value uint16) {
+		if ram[a] != value {
+			failures = append(failures, fmt.Sprintf("RAM[%d] = %d, expected %d", a, int16(ram[a]), int16(value)))
+		}
+	}, expect)
+	if err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// dumpRAM 输出 "0-15,256" 这样的地址范围中的 RAM
+func dumpRAM(ram *[cpu.RAM_SIZE]uint16, ranges string) error {
+	for _, item := range strings.Split(ranges, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		first, last, ok := strings.Cut(item, "-")
+		if !ok {
+			last = first
+		}
+		from, err := parseAddress(first)
+		if err != nil {
+			return err
+		}
+		to, err := parseAddress(last)
+		if err != nil {
+			return err
+		}
+		for a := from; a <= to; a++ {
+			fmt.Printf("RAM[%d] = %d\n", a, int16(ram[a]))
+		}
+	}
+	return nil
+}
+
+func parseAddress(text string) (int, error) {
+	address, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || address < 0 || address >= cpu.RAM_SIZE {
+		return 0, fmt.Errorf("invalid RAM address %q", text)
+	}
+	return address, nil
+}
@@ -0,0 +1,272 @@
+// Package profile 统计 CPU 模拟器执行程序时每个函数和 label 区域用掉的周期数。
+// label 按 08/translator 的命名规则归属到函数：Function$label 属于 Function，不含 $ 的 label 本身就是函数，
+// $ 开头的 label（比较命令生成的 $TRUE$n 等）不算新的区域。调用栈由 LCL 链上保存的返回地址
+// （Function$ret.n）得到，用于计算包含被调用函数的总周期数和火焰图的 folded stack
+package profile
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"hongkuancn/nand2tetris/assembler/asm"
+	"hongkuancn/nand2tetris/emulator/cpu"
+)
+
+const (
+	// TOP 是第一个 label 之前的代码所在的区域，如引导代码
+	TOP = "(top)"
+	// MAX_DEPTH 限制沿 LCL 链查找调用者的层数，防止 RAM 被破坏时死循环
+	MAX_DEPTH = 1024
+	// LCL 是保存当前帧地址的寄存器
+	LCL = 1
+)
+
+// region 是从一个 label 开始到下一个 label 之前的代码
+type region struct {
+	label    string
+	function string
+	cycles   uint64
+}
+
+type Profiler struct {
+	CPU *cpu.CPU
+
+	regions []region
+	// regionOf 是每个 ROM 地址所在的区域，entries 是每个函数入口地址对应的函数
+	regionOf []int
+	entries  map[uint16]string
+	// returns 是每个返回地址（Function$ret.n）所属的函数
+	returns map[uint16]string
+	calls   map[string]uint64
+	// stacks 是每个调用栈（用 ; 连接的函数名，最外层在前）用掉的周期数
+	stacks   map[string]uint64
+	stack    string
+	function string
+}
+
+// New 创建 profiler，prog 是 c 中程序的汇编结果，为 nil 时所有周期都算在 TOP 中
+func New(c *cpu.CPU, prog *asm.Program) *Profiler {
+	p := &Profiler{
+		CPU:      c,
+		regions:  []region{{label: TOP, function: TOP}},
+		regionOf: make([]int, c.Size),
+		entries:  make(map[uint16]string),
+		returns:  make(map[uint16]string),
+		calls:    make(map[string]uint64),
+		stacks:   make(map[string]uint64),
+	}
+	labels := make([]asm.Symbol, 0)
+	if prog != nil {
+		for _, sym := range prog.Symbols.Symbols() {
+			if sym.Kind == asm.SYM_LABEL && !strings.HasPrefix(sym.Name, "$") {
+				labels = append(labels, sym)
+			}
+		}
+	}
+	// Symbols 已经按地址排序。同一地址有多个 label 时区域用函数入口的名字，如引导代码的返回地址紧接着 Sys.init，
+	// 后面的代码属于 Sys.init；没有函数入口时用第一个 label
+	starts := make(map[int]int)
+	for _, sym := range labels {
+		function, _, local := strings.Cut(sym.Name, "$")
+		if !local {
+			p.entries[uint16(sym.Address)] = function
+		}
+		if strings.Contains(sym.Name, "$ret.") {
+			p.returns[uint16(sym.Address)] = function
+		}
+		if sym.Address >= c.Size {
+			continue
+		}
+		if i, ok := starts[sym.Address]; !ok {
+			starts[sym.Address] = len(p.regions)
+			p.regions = append(p.regions, region{label: sym.Name, function: function})
+		} else if !local && strings.Contains(p.regions[i].label, "$") {
+			p.regions[i] = region{label: sym.Name, function: function}
+		}
+	}
+	current := 0
+	for a := range p.regionOf {
+		if i, ok := starts[a]; ok {
+			current = i
+		}
+		p.regionOf[a] = current
+	}
+	return p
+}
+
+// Step 执行一条指令并记录它的周期
+func (p *Profiler) Step() error {
+	c := p.CPU
+	pc := c.PC
+	if int(pc) < len(p.regionOf) {
+		r := &p.regions[p.regionOf[pc]]
+		r.cycles += 1
+		if r.function != p.function {
+			p.update()
+		}
+	}
+	p.stacks[p.stack] += 1
+	instruction, target := c.ROM[pc], c.A&cpu.ADDRESS_MASK
+	if err := c.Step(); err != nil {
+		return err
+	}
+	// 跳到下一条指令的跳转也算，引导代码调用紧接在后面的 Sys.init 就是这样
+	jumped := instruction&0x8000 != 0 && instruction&cpu.JUMP_MASK != 0 && c.PC == target
+	if jumped || c.PC != pc+1 {
+		if function, ok := p.entries[c.PC]; ok && jumped {
+			p.calls[function] += 1
+		}
+		p.update()
+	}
+	return nil
+}
+
+// update 在跳转或者进入另一个函数的代码后重新计算调用栈
+func (p *Profiler) update() {
+	c := p.CPU
+	p.function = TOP
+	if int(c.PC) < len(p.regionOf) {
+		p.function = p.regions[p.regionOf[c.PC]].function
+	}
+	names := []string{p.function}
+	lcl := c.RAM[LCL]
+	for depth := 0; depth < MAX_DEPTH && lcl >= 5; depth++ {
+		caller, ok := p.returns[c.RAM[lcl-5]]
+		if !ok {
+			break
+		}
+		names = append(names, caller)
+		lcl = c.RAM[lcl-4]
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	p.stack = strings.Join(names, ";")
+}
+
+// Run 执行最多 budget 条指令，同 cpu.CPU.Run
+func (p *Profiler) Run(budget uint64) (string, error) {
+	if p.stack == "" {
+		p.update()
+	}
+	for i := uint64(0); i < budget && !p.CPU.Halted(); i++ {
+		if err := p.Step(); err != nil {
+			return "", err
+		}
+	}
+	if p.CPU.Halted() {
+		return cpu.HALT_LOOP, nil
+	}
+	return cpu.HALT_BUDGET, nil
+}
+
+func (p *Profiler) SetKey(key uint16) {
+	p.CPU.SetKey(key)
+}
+
+// Entry 是报告中的一行
+type Entry struct {
+	Name  string
+	Calls uint64
+	// Self 是在自己的代码中用掉的周期，Total 还包括它调用的函数
+	Self  uint64
+	Total uint64
+}
+
+// Functions 返回每个函数的统计，按 Self 从大到小排序
+func (p *Profiler) Functions() []Entry {
+	byName := make(map[string]*Entry)
+	entry := func(name string) *Entry {
+		if byName[name] == nil {
+			byName[name] = &Entry{Name: name}
+		}
+		return byName[name]
+	}
+	for _, r := range p.regions {
+		entry(r.function).Self += r.cycles
+	}
+	for function, calls := range p.calls {
+		entry(function).Calls = calls
+	}
+	// 递归调用时同一个函数在栈中出现多次，只算一次
+	for stack, cycles := range p.stacks {
+		seen := make(map[string]bool)
+		for _, name := range strings.Split(stack, ";") {
+			if !seen[name] {
+				seen[name] = true
+				entry(name).Total += cycles
+			}
+		}
+	}
+	entries := make([]Entry, 0, len(byName))
+	for _, e := range byName {
+		if e.Self > 0 || e.Total > 0 || e.Calls > 0 {
+			entries = append(entries, *e)
+		}
+	}
+	sortEntries(entries)
+	return entries
+}
+
+// Labels 返回每个 label 区域的统计，按 Self 从大到小排序，没有执行过的区域不在其中
+func (p *Profiler) Labels() []Entry {
+	entries := make([]Entry, 0)
+	for _, r := range p.regions {
+		if r.cycles > 0 {
+			entries = append(entries, Entry{Name: r.label, Self: r.cycles, Total: r.cycles})
+		}
+	}
+	sortEntries(entries)
+	return entries
+}
+
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Self != entries[j].Self {
+			return entries[i].Self > entries[j].Self
+		}
+		return entries[i].Name < entries[j].Name
+	})
+}
+
+// Report 输出函数和 label 的统计，labels 限制输出的 label 数，为 0 时全部输出
+func (p *Profiler) Report(w io.Writer, labels int) {
+	total := p.CPU.Cycles
+	percent := func(n uint64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return 100 * float64(n) / float64(total)
+	}
+	fmt.Fprintf(w, "%-40s %10s %12s %6s %12s %6s\n", "FUNCTION", "CALLS", "SELF", "%", "TOTAL", "%")
+	for _, e := range p.Functions() {
+		fmt.Fprintf(w, "%-40s %10d %12d %5.1f%% %12d %5.1f%%\n", e.Name, e.Calls, e.Self, percent(e.Self), e.Total, percent(e.Total))
+	}
+	fmt.Fprintf(w, "\n%-40s %10s %12s %6s\n", "LABEL", "", "CYCLES", "%")
+	entries := p.Labels()
+	for i, e := range entries {
+		if labels > 0 && i == labels {
+			fmt.Fprintf(w, "... %d more labels\n", len(entries)-i)
+			break
+		}
+		fmt.Fprintf(w, "%-40s %10s %12d %5.1f%%\n", e.Name, "", e.Self, percent(e.Self))
+	}
+	fmt.Fprintf(w, "\n%d cycles\n", total)
+}
+
+// WriteFolded 按 flamegraph.pl 的 folded 格式输出每个调用栈的周期数，每行是 "f1;f2;f3 周期数"
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	stacks := make([]string, 0, len(p.stacks))
+	for stack := range p.stacks {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, p.stacks[stack]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
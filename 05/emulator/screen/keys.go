@@ -0,0 +1,94 @@
+package screen
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Hack 键盘的特殊键码，可打印字符的键码就是它的 ASCII 码
+const (
+	KEY_NEWLINE   = 128
+	KEY_BACKSPACE = 129
+	KEY_LEFT      = 130
+	KEY_UP        = 131
+	KEY_RIGHT     = 132
+	KEY_DOWN      = 133
+	KEY_HOME      = 134
+	KEY_END       = 135
+	KEY_PAGE_UP   = 136
+	KEY_PAGE_DOWN = 137
+	KEY_INSERT    = 138
+	KEY_DELETE    = 139
+	KEY_ESC       = 140
+	// KEY_F1 到 F12 是 141-152
+	KEY_F1 = 141
+)
+
+// KEY_QUIT 是 DecodeKey 对 Ctrl-C 和 Ctrl-D 返回的值，不是 Hack 键码
+const KEY_QUIT = 0xffff
+
+// 终端转义序列 ESC [ ... 和 ESC O ... 去掉前缀后的部分
+var escapeKeys = map[string]uint16{
+	"A": KEY_UP, "B": KEY_DOWN, "C": KEY_RIGHT, "D": KEY_LEFT,
+	"H": KEY_HOME, "F": KEY_END, "1~": KEY_HOME, "4~": KEY_END, "7~": KEY_HOME, "8~": KEY_END,
+	"2~": KEY_INSERT, "3~": KEY_DELETE, "5~": KEY_PAGE_UP, "6~": KEY_PAGE_DOWN,
+	"P": KEY_F1, "Q": KEY_F1 + 1, "R": KEY_F1 + 2, "S": KEY_F1 + 3,
+	"11~": KEY_F1, "12~": KEY_F1 + 1, "13~": KEY_F1 + 2, "14~": KEY_F1 + 3,
+	"15~": KEY_F1 + 4, "17~": KEY_F1 + 5, "18~": KEY_F1 + 6, "19~": KEY_F1 + 7,
+	"20~": KEY_F1 + 8, "21~": KEY_F1 + 9, "23~": KEY_F1 + 10, "24~": KEY_F1 + 11,
+}
+
+// keyNames 是按键脚本中特殊键的名字
+var keyNames = map[string]uint16{
+	"newline": KEY_NEWLINE, "enter": KEY_NEWLINE, "backspace": KEY_BACKSPACE,
+	"left": KEY_LEFT, "up": KEY_UP, "right": KEY_RIGHT, "down": KEY_DOWN,
+	"home": KEY_HOME, "end": KEY_END, "pageup": KEY_PAGE_UP, "pagedown": KEY_PAGE_DOWN,
+	"insert": KEY_INSERT, "delete": KEY_DELETE, "esc": KEY_ESC, "space": ' ',
+	"f1": KEY_F1, "f2": KEY_F1 + 1, "f3": KEY_F1 + 2, "f4": KEY_F1 + 3,
+	"f5": KEY_F1 + 4, "f6": KEY_F1 + 5, "f7": KEY_F1 + 6, "f8": KEY_F1 + 7,
+	"f9": KEY_F1 + 8, "f10": KEY_F1 + 9, "f11": KEY_F1 + 10, "f12": KEY_F1 + 11,
+}
+
+// KeyCode 把按键转换为 Hack 键码：单个字符、特殊键的名字（如 left、f1、enter）或者多位的十进制键码
+func KeyCode(name string) (uint16, bool) {
+	if code, ok := keyNames[strings.ToLower(name)]; ok {
+		return code, true
+	}
+	if len(name) == 1 && name[0] >= 32 && name[0] < 127 {
+		return uint16(name[0]), true
+	}
+	if code, err := strconv.Atoi(name); err == nil && code > 0 && code < 32768 {
+		return uint16(code), true
+	}
+	return 0, false
+}
+
+// DecodeKey 解码终端输入开头的一个按键，返回 Hack 键码和用掉的字节数。不认识的输入键码为 0
+func DecodeKey(input []byte) (uint16, int) {
+	if len(input) == 0 {
+		return 0, 0
+	}
+	c := input[0]
+	switch {
+	case c == 3 || c == 4:
+		return KEY_QUIT, 1
+	case c == '\r' || c == '\n':
+		return KEY_NEWLINE, 1
+	case c == 0x7f || c == 0x08:
+		return KEY_BACKSPACE, 1
+	case c >= 32 && c < 127:
+		return uint16(c), 1
+	case c != 0x1b:
+		return 0, 1
+	}
+	if len(input) < 2 || input[1] != '[' && input[1] != 'O' {
+		return KEY_ESC, 1
+	}
+	// 转义序列以 0x40-0x7e 之间的字节结束
+	for i := 2; i < len(input); i++ {
+		if input[i] >= 0x40 && input[i] <= 0x7e {
+			return escapeKeys[string(input[2:i+1])], i + 1
+		}
+	}
+	return 0, len(input)
+}
@@ -0,0 +1,184 @@
+package screen
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"hongkuancn/nand2tetris/emulator/cpu"
+)
+
+const (
+	// PRESS_CYCLES 是 press 没有给出 for 时按键保持的周期数
+	PRESS_CYCLES = 200000
+	// TYPE_CYCLES 是 type 中每个字符按下的周期数，KEY_GAP 是两次按键之间松开的周期数，
+	// 程序要看到键盘寄存器变回 0 才能区分连续两次按同一个键
+	TYPE_CYCLES = 50000
+	KEY_GAP     = 50000
+)
+
+// KeyEvent 是脚本中的一次按键：从周期 At 开始按下 Key，保持 Cycles 个周期
+type KeyEvent struct {
+	At     uint64
+	Cycles uint64
+	Key    uint16
+	Line   int
+}
+
+// KeyScript 是按时间顺序排列、互不重叠的按键
+type KeyScript []KeyEvent
+
+// ParseKeyScript 解析按键脚本，每行一条命令，// 开头的行是注释：
+//
+//	at cycle 1000 press a for 5000 cycles
+//	at cycle 8000 press key left for 20000 cycles
+//	press space
+//	type string hello world
+//	at cycle 900000 type string "quoted, with trailing space "
+//
+// press 后面的 key 可以省略。没有 at cycle 的命令在上一条命令结束后 KEY_GAP 个周期开始
+func ParseKeyScript(src string) (KeyScript, error) {
+	script := KeyScript{}
+	next := uint64(0)
+	for n, raw := range strings.Split(src, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		errorf := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %s", n+1, fmt.Sprintf(format, args...))
+		}
+		words := strings.Fields(line)
+		start := next
+		if words[0] == "at" {
+			if len(words) < 3 || words[1] != "cycle" {
+				return nil, errorf("expected \"at cycle N\"")
+			}
+			at, err := strconv.ParseUint(words[2], 10, 64)
+			if err != nil {
+				return nil, errorf("invalid cycle %q", words[2])
+			}
+			if len(script) > 0 && at < next-KEY_GAP {
+				return nil, errorf("cycle %d is before the end of the previous key at cycle %d", at, next-KEY_GAP)
+			}
+			start = at
+			words = words[3:]
+			if len(words) == 0 {
+				return nil, errorf("expected press or type")
+			}
+			// 保留 type string 后面文本中的空白
+			line = strings.TrimSpace(line[strings.Index(line, words[0]):])
+		}
+
+		switch words[0] {
+		case "press":
+			if len(words) > 2 && words[1] == "key" {
+				words = append(words[:1], words[2:]...)
+			}
+			if len(words) != 2 && len(words) != 5 {
+				return nil, errorf("expected \"press [key] KEY [for N cycles]\"")
+			}
+			key, ok := KeyCode(words[1])
+			if !ok {
+				return nil, errorf("unknown key %q", words[1])
+			}
+			cycles := uint64(PRESS_CYCLES)
+			if len(words) == 5 {
+				var err error
+				cycles, err = strconv.ParseUint(words[3], 10, 64)
+				if words[2] != "for" || words[4] != "cycles" || err != nil || cycles == 0 {
+					return nil, errorf("expected \"for N cycles\" with N > 0")
+				}
+			}
+			script = append(script, KeyEvent{At: start, Cycles: cycles, Key: key, Line: n + 1})
+			next = start + cycles + KEY_GAP
+		case "type":
+			if len(words) < 3 || words[1] != "string" {
+				return nil, errorf("expected \"type string TEXT\"")
+			}
+			text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line[len("type"):]), "string"))
+			if strings.HasPrefix(text, "\"") {
+				unquoted, err := strconv.Unquote(text)
+				if err != nil {
+					return nil, errorf("invalid quoted string %s", text)
+				}
+				text = unquoted
+			}
+			for _, c := range text {
+				key := uint16(c)
+				if c == '\n' {
+					key = KEY_NEWLINE
+				} else if c < 32 || c >= 127 {
+					return nil, errorf("cannot type %q on the Hack keyboard", c)
+				}
+				script = append(script, KeyEvent{At: start, Cycles: TYPE_CYCLES, Key: key, Line: n + 1})
+				start += TYPE_CYCLES + KEY_GAP
+			}
+			next = start
+		default:
+			return nil, errorf("unknown command %q, expected press or type", words[0])
+		}
+	}
+	return script, nil
+}
+
+// KeyAt 返回周期 cycle 时按下的键，没有按键时为 0
+func (s KeyScript) KeyAt(cycle uint64) uint16 {
+	for _, event := range s {
+		if cycle >= event.At && cycle < event.At+event.Cycles {
+			return event.Key
+		}
+	}
+	return 0
+}
+
+// Next 返回 cycle 之后键盘寄存器下一次变化的周期，没有变化时是 math.MaxUint64
+func (s KeyScript) Next(cycle uint64) uint64 {
+	for _, event := range s {
+		if event.At > cycle {
+			return event.At
+		}
+		if event.At+event.Cycles > cycle {
+			return event.At + event.Cycles
+		}
+	}
+	return math.MaxUint64
+}
+
+// Scripted 按脚本驱动键盘寄存器，周期数从开始执行时算起
+type Scripted struct {
+	Machine
+	Script KeyScript
+	// Cycle 是已经过去的周期数。程序停止（如等待按键的循环被检测为死循环）时时间直接跳到下一次按键
+	Cycle uint64
+	key   uint16
+}
+
+func NewScripted(m Machine, script KeyScript) *Scripted {
+	return &Scripted{Machine: m, Script: script}
+}
+
+// Run 执行 budget 个周期，在每次按下和松开时设置键盘寄存器。程序停止而且之后没有按键时返回 cpu.HALT_LOOP
+func (s *Scripted) Run(budget uint64) (string, error) {
+	end := s.Cycle + budget
+	for s.Cycle < end {
+		if key := s.Script.KeyAt(s.Cycle); key != s.key {
+			s.key = key
+			s.Machine.SetKey(key)
+		}
+		next := s.Script.Next(s.Cycle)
+		if next > end {
+			next = end
+		}
+		reason, err := s.Machine.Run(next - s.Cycle)
+		if err != nil {
+			return "", err
+		}
+		if reason == cpu.HALT_LOOP && s.Script.Next(s.Cycle) == math.MaxUint64 {
+			return cpu.HALT_LOOP, nil
+		}
+		s.Cycle = next
+	}
+	return cpu.HALT_BUDGET, nil
+}
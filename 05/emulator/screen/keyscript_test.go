@@ -0,0 +1,54 @@
+package screen
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseKeyScript 解析请求中的写法 at cycle N press key K for M cycles，key 可以省略
+func TestParseKeyScript(t *testing.T) {
+	script, err := ParseKeyScript(`at cycle 1000 press key a for 5000 cycles
+press left for 20000 cycles
+at cycle 100000 press key space
+type string hi`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := KeyScript{
+		{At: 1000, Cycles: 5000, Key: 'a', Line: 1},
+		{At: 1000 + 5000 + KEY_GAP, Cycles: 20000, Key: KEY_LEFT, Line: 2},
+		{At: 100000, Cycles: PRESS_CYCLES, Key: ' ', Line: 3},
+		{At: 100000 + PRESS_CYCLES + KEY_GAP, Cycles: TYPE_CYCLES, Key: 'h', Line: 4},
+		{At: 100000 + PRESS_CYCLES + KEY_GAP + TYPE_CYCLES + KEY_GAP, Cycles: TYPE_CYCLES, Key: 'i', Line: 4},
+	}
+	if len(script) != len(want) {
+		t.Fatalf("got %d events %v, want %v", len(script), script, want)
+	}
+	for i := range want {
+		if script[i] != want[i] {
+			t.Errorf("event %d is %+v, want %+v", i, script[i], want[i])
+		}
+	}
+}
+
+// TestParseKeyScriptErrors 检查不完整的命令报告行号和错误，而不是 panic
+func TestParseKeyScriptErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"at cycle 5", "line 1: expected press or type"},
+		{"// comment\nat cycle 5   ", "line 2: expected press or type"},
+		{"at 5 press a", "line 1: expected \"at cycle N\""},
+		{"press", "line 1: expected \"press [key] KEY [for N cycles]\""},
+		{"press key", "line 1: unknown key \"key\""},
+		{"press key a for 5", "line 1: expected \"press [key] KEY [for N cycles]\""},
+		{"type string", "line 1: expected \"type string TEXT\""},
+	}
+	for _, test := range tests {
+		_, err := ParseKeyScript(test.src)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("ParseKeyScript(%q) = %v, want %q", test.src, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package screen
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/png"
+	"math"
+	"os"
+
+	"hongkuancn/nand2tetris/emulator/cpu"
+)
+
+var palette = color.Palette{color.White, color.Black}
+
+// Image 返回屏幕的 512x256 黑白图像
+func Image(ram *[cpu.RAM_SIZE]uint16) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, WIDTH, HEIGHT), palette)
+	for y := 0; y < HEIGHT; y++ {
+		for x := 0; x < WIDTH; x++ {
+			if Pixel(ram, x, y) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return img
+}
+
+// SavePNG 把屏幕保存为 PNG 文件
+func SavePNG(file string, ram *[cpu.RAM_SIZE]uint16) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, Image(ram)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadImage 读取 PNG 或 GIF 图像
+func LoadImage(file string) (image.Image, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return img, nil
+}
+
+const (
+	// WHITE 是截图中屏幕背景的最低亮度，用来找到灰色界面中的屏幕
+	WHITE = 250
+	// GRAY_TOLERANCE 是缩小的截图中一个像素的亮度和屏幕对应区域的平均亮度最多相差多少，
+	// 截图缩小时线条的边缘被平滑成灰色
+	GRAY_TOLERANCE = 128
+	// SCALED_TOLERANCE 是和缩小的截图比较时默认允许不同的像素的百分比
+	SCALED_TOLERANCE = 1.0
+	// ALIGN 是对齐截图时位置和大小的搜索范围
+	ALIGN = 2
+)
+
+// Difference 是两幅屏幕图像的差别
+type Difference struct {
+	// Pixels 是不同的像素数，Total 是比较的像素数，Bounds 是包含不同像素的最小矩形
+	Pixels int
+	Total  int
+	Bounds image.Rectangle
+	// First 是按行扫描时的前几个不同的像素
+	First []image.Point
+	// Scaled 表示参考图像是缩小的截图，坐标都已换算为屏幕上的像素
+	Scaled bool
+}
+
+// Percent 返回不同的像素占比较的像素的百分比
+func (d Difference) Percent() float64 {
+	if d.Total == 0 {
+		return 0
+	}
+	return 100 * float64(d.Pixels) / float64(d.Total)
+}
+
+func (d Difference) String() string {
+	if d.Pixels == 0 {
+		return "screens are identical"
+	}
+	return fmt.Sprintf("%d pixels (%.2f%%) differ within (%d,%d)-(%d,%d), first at %v",
+		d.Pixels, d.Percent(), d.Bounds.Min.X, d.Bounds.Min.Y, d.Bounds.Max.X-1, d.Bounds.Max.Y-1, d.First)
+}
+
+func (d *Difference) add(x int, y int, w int, h int) {
+	d.Pixels += 1
+	d.Bounds = d.Bounds.Union(image.Rect(x, y, x+w, y+h))
+	if len(d.First) < 5 {
+		d.First = append(d.First, image.Pt(x, y))
+	}
+}
+
+// gray 返回像素的亮度
+func gray(c color.Color) int {
+	return int(color.GrayModel.Convert(c).(color.Gray).Y)
+}
+
+// isBlack 按亮度把像素分为黑白两种，参考图像可以是灰度或彩色的
+func isBlack(c color.Color) bool {
+	return gray(c) < 128
+}
+
+// ScreenArea 返回参考图像中屏幕所在的矩形。512x256 的图像整个都是屏幕；其他尺寸的图像当作
+// Java 模拟器缩小了的截图（如 12/OutputTest/OutputTestOutput.gif），屏幕是灰色界面中的白色区域。
+// 宽度取白色像素的范围，高度按屏幕 2:1 的比例计算，截图的下边缘常常和界面的边框混在一起
+func ScreenArea(img image.Image) (image.Rectangle, error) {
+	b := img.Bounds()
+	if b.Size() == image.Pt(WIDTH, HEIGHT) {
+		return b, nil
+	}
+	area := image.Rectangle{}
+	found := false
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if gray(img.At(x, y)) < WHITE {
+				continue
+			}
+			if !found {
+				area = image.Rect(x, y, x+1, y+1)
+				found = true
+			}
+			area = area.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+	w := area.Dx()
+	if !found || w < WIDTH/8 {
+		return area, fmt.Errorf("cannot find the screen in a %dx%d image", b.Dx(), b.Dy())
+	}
+	area.Max.Y = area.Min.Y + (w+1)/2
+	if area.Max.Y > b.Max.Y || w > WIDTH {
+		return area, fmt.Errorf("cannot find the screen in a %dx%d image", b.Dx(), b.Dy())
+	}
+	return area, nil
+}
+
+// Compare 比较屏幕图像 got（512x256）和参考图像 want。want 是 512x256 时逐个像素比较黑白；
+// 是缩小的截图时，截图中的每个像素和屏幕上对应区域的平均亮度比较，相差超过 GRAY_TOLERANCE 算作不同
+func Compare(got image.Image, want image.Image) (Difference, error) {
+	diff := Difference{}
+	if size := got.Bounds().Size(); size != image.Pt(WIDTH, HEIGHT) {
+		return diff, fmt.Errorf("image is %dx%d, expected a %dx%d screen snapshot", size.X, size.Y, WIDTH, HEIGHT)
+	}
+	area, err := ScreenArea(want)
+	if err != nil {
+		return diff, err
+	}
+	g := got.Bounds().Min
+	if area.Size() == image.Pt(WIDTH, HEIGHT) {
+		diff.Total = WIDTH * HEIGHT
+		for y := 0; y < HEIGHT; y++ {
+			for x := 0; x < WIDTH; x++ {
+				if isBlack(got.At(g.X+x, g.Y+y)) != isBlack(want.At(area.Min.X+x, area.Min.Y+y)) {
+					diff.add(x, y, 1, 1)
+				}
+			}
+		}
+		return diff, nil
+	}
+
+	// 截图的缩放比例不一定精确，在 ScreenArea 估计的位置和大小附近搜索不同像素最少的对齐方式
+	scaled := newScaled(got, want)
+	best := area
+	fewest := scaled.compare(area, nil, math.MaxInt)
+	for w := area.Dx() - ALIGN; w <= area.Dx()+ALIGN; w++ {
+		for h := w/2 - ALIGN; h <= w/2+ALIGN; h++ {
+			for dy := -ALIGN; dy <= ALIGN; dy++ {
+				for dx := -ALIGN; dx <= ALIGN; dx++ {
+					r := image.Rect(area.Min.X+dx, area.Min.Y+dy, area.Min.X+dx+w, area.Min.Y+dy+h)
+					if !r.In(want.Bounds()) || w > WIDTH || h > HEIGHT {
+						continue
+					}
+					if n := scaled.compare(r, nil, fewest); n < fewest {
+						best, fewest = r, n
+					}
+				}
+			}
+		}
+	}
+	diff.Scaled = true
+	diff.Total = best.Dx() * best.Dy()
+	scaled.compare(best, &diff, math.MaxInt)
+	return diff, nil
+}
+
+// scaled 比较屏幕和缩小的截图。black 是屏幕上黑色像素数的二维前缀和，用来快速计算任意区域的平均亮度，
+// gray 是截图中每个像素的亮度
+type scaled struct {
+	black  [HEIGHT + 1][WIDTH + 1]int
+	bounds image.Rectangle
+	gray   []int
+}
+
+func newScaled(got image.Image, want image.Image) *scaled {
+	b := want.Bounds()
+	s := &scaled{bounds: b, gray: make([]int, 0, b.Dx()*b.Dy())}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			s.gray = append(s.gray, gray(want.At(x, y)))
+		}
+	}
+	g := got.Bounds().Min
+	for y := 0; y < HEIGHT; y++ {
+		for x := 0; x < WIDTH; x++ {
+			n := 0
+			if isBlack(got.At(g.X+x, g.Y+y)) {
+				n = 1
+			}
+			s.black[y+1][x+1] = n + s.black[y][x+1] + s.black[y+1][x] - s.black[y][x]
+		}
+	}
+	return s
+}
+
+// compare 把截图中 area 的部分和整个屏幕比较，返回不同的像素数。截图中的像素 (x, y) 对应屏幕上
+// [x0, x1) x [y0, y1) 的区域，两者的亮度相差超过 GRAY_TOLERANCE 算作不同。diff 不为 nil 时记录不同的像素，
+// 不同的像素达到 limit 时不再继续比较
+func (s *scaled) compare(area image.Rectangle, diff *Difference, limit int) int {
+	n := 0
+	for y := 0; y < area.Dy() && n < limit; y++ {
+		y0, y1 := y*HEIGHT/area.Dy(), (y+1)*HEIGHT/area.Dy()
+		for x := 0; x < area.Dx(); x++ {
+			x0, x1 := x*WIDTH/area.Dx(), (x+1)*WIDTH/area.Dx()
+			black := s.black[y1][x1] - s.black[y0][x1] - s.black[y1][x0] + s.black[y0][x0]
+			pixels := (x1 - x0) * (y1 - y0)
+			expected := 255 * (pixels - black) / pixels
+			actual := s.gray[(area.Min.Y+y-s.bounds.Min.Y)*s.bounds.Dx()+area.Min.X+x-s.bounds.Min.X]
+			if expected-actual > GRAY_TOLERANCE || actual-expected > GRAY_TOLERANCE {
+				n += 1
+				if diff != nil {
+					diff.add(x0, y0, x1-x0, y1-y0)
+				}
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,61 @@
+package screen
+
+import (
+	"image"
+	"testing"
+
+	"hongkuancn/nand2tetris/emulator/cpu"
+)
+
+// OUTPUT_TEST 是 12/OutputTest 在 VM 模拟器中运行结束时的屏幕，OS 是 12 中的实现
+const OUTPUT_TEST = "testdata/OutputTest.png"
+
+func load(t *testing.T, file string) image.Image {
+	img, err := LoadImage(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+// TestCompareScaledScreenshot 用 12 中缩小了的截图比较：同一个程序的屏幕在 SCALED_TOLERANCE 以内，
+// 其他程序和空白屏幕都超出
+func TestCompareScaledScreenshot(t *testing.T) {
+	got := load(t, OUTPUT_TEST)
+	blank := Image(&[cpu.RAM_SIZE]uint16{})
+	tests := []struct {
+		got   image.Image
+		want  string
+		match bool
+	}{
+		{got, "../../../12/OutputTest/OutputTestOutput.gif", true},
+		{got, "../../../12/StringTest/StringTestOutput.gif", false},
+		{got, "../../../12/ScreenTest/ScreenTestOutput.gif", false},
+		{blank, "../../../12/OutputTest/OutputTestOutput.gif", false},
+	}
+	for _, test := range tests {
+		diff, err := Compare(test.got, load(t, test.want))
+		if err != nil {
+			t.Fatalf("%s: %v", test.want, err)
+		}
+		if !diff.Scaled {
+			t.Errorf("%s: expected a scaled comparison", test.want)
+		}
+		if match := diff.Percent() <= SCALED_TOLERANCE; match != test.match {
+			t.Errorf("%s: match = %v, want %v: %v", test.want, match, test.match, diff)
+		}
+	}
+}
+
+// TestCompareSnapshot 用 512x256 的图像比较时逐个像素比较
+func TestCompareSnapshot(t *testing.T) {
+	got := load(t, OUTPUT_TEST)
+	diff, err := Compare(got, got)
+	if err != nil || diff.Pixels != 0 || diff.Scaled {
+		t.Fatalf("comparing a snapshot with itself: %v, %v", diff, err)
+	}
+	diff, err = Compare(Image(&[cpu.RAM_SIZE]uint16{}), got)
+	if err != nil || diff.Pixels == 0 || diff.Total != WIDTH*HEIGHT {
+		t.Fatalf("comparing a blank screen with a snapshot: %v, %v", diff, err)
+	}
+}
@@ -0,0 +1,91 @@
+// Package screen 在终端中显示 Hack 计算机的屏幕，并把按键送入键盘寄存器。
+// 屏幕是从 SCREEN 开始的 8K 个字，每行 512 个像素占 32 个字，字的最低位是最左边的像素，1 为黑色
+package screen
+
+import (
+	"fmt"
+	"strings"
+
+	"hongkuancn/nand2tetris/emulator/cpu"
+)
+
+const (
+	WIDTH  = 512
+	HEIGHT = 256
+	// ROW_WORDS 是每行像素占的字数
+	ROW_WORDS = WIDTH / 16
+)
+
+// 显示方式
+const (
+	// BRAILLE 用盲文字符，每个字符 2x4 个点
+	BRAILLE = "braille"
+	// HALF_BLOCK 用半格字符，每个字符 1x2 个点
+	HALF_BLOCK = "half"
+)
+
+// Pixel 判断屏幕上 (x, y) 处的像素是否是黑色
+func Pixel(ram *[cpu.RAM_SIZE]uint16, x int, y int) bool {
+	word := ram[cpu.SCREEN+y*ROW_WORDS+x/16]
+	return word>>(x%16)&1 != 0
+}
+
+// dot 判断缩小 scale 倍后 (x, y) 处的点是否是黑色，对应的 scale x scale 个像素中有一个是黑色就算黑色
+func dot(ram *[cpu.RAM_SIZE]uint16, x int, y int, scale int) bool {
+	for dy := 0; dy < scale; dy++ {
+		for dx := 0; dx < scale; dx++ {
+			px, py := x*scale+dx, y*scale+dy
+			if px < WIDTH && py < HEIGHT && Pixel(ram, px, py) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 盲文字符 U+2800 中每个点对应的位，下标是 [行][列]
+var brailleBits = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// Render 把屏幕画成文本，每行以 \n 结束。scale 把屏幕缩小为原来的 1/scale，终端放不下整个屏幕时使用
+func Render(ram *[cpu.RAM_SIZE]uint16, mode string, scale int) (string, error) {
+	if scale < 1 {
+		return "", fmt.Errorf("invalid scale %d", scale)
+	}
+	width, height := (WIDTH+scale-1)/scale, (HEIGHT+scale-1)/scale
+	builder := strings.Builder{}
+	switch mode {
+	case BRAILLE:
+		for y := 0; y < height; y += 4 {
+			for x := 0; x < width; x += 2 {
+				char := rune(0x2800)
+				for row := 0; row < 4; row++ {
+					for col := 0; col < 2; col++ {
+						if dot(ram, x+col, y+row, scale) {
+							char |= brailleBits[row][col]
+						}
+					}
+				}
+				builder.WriteRune(char)
+			}
+			builder.WriteString("\n")
+		}
+	case HALF_BLOCK:
+		chars := []string{" ", "▀", "▄", "█"}
+		for y := 0; y < height; y += 2 {
+			for x := 0; x < width; x++ {
+				i := 0
+				if dot(ram, x, y, scale) {
+					i |= 1
+				}
+				if dot(ram, x, y+1, scale) {
+					i |= 2
+				}
+				builder.WriteString(chars[i])
+			}
+			builder.WriteString("\n")
+		}
+	default:
+		return "", fmt.Errorf("unknown screen mode %q, expected %s or %s", mode, BRAILLE, HALF_BLOCK)
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,124 @@
+package screen
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"hongkuancn/nand2tetris/emulator/cpu"
+)
+
+const (
+	// FRAME 是刷新屏幕的间隔
+	FRAME = time.Second / 30
+	// KEY_HOLD 是按键保持按下的时间。终端只有按下没有松开，按住不放时终端会重复发送，
+	// 所以在最后一次收到之后保持一段时间再松开
+	KEY_HOLD = 150 * time.Millisecond
+)
+
+// Machine 是在终端中运行的模拟器，cpu.CPU 和 vm.VM 都实现了它
+type Machine interface {
+	Run(budget uint64) (string, error)
+	SetKey(key uint16)
+}
+
+// Options 控制终端显示
+type Options struct {
+	// Mode 是 BRAILLE 或 HALF_BLOCK
+	Mode  string
+	Scale int
+	// Speed 是每秒执行的指令数（VM 程序是命令数）
+	Speed uint64
+}
+
+// Play 在终端中运行程序，直到按下 Ctrl-C 或 Ctrl-D。程序停止后仍然显示屏幕并接收按键，
+// 因为等待按键的程序会被当作进入了死循环，按键改变键盘寄存器后又会继续执行
+func Play(m Machine, ram *[cpu.RAM_SIZE]uint16, opts Options) error {
+	if _, err := Render(ram, opts.Mode, opts.Scale); err != nil {
+		return err
+	}
+	restore, err := rawMode()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	fmt.Print("\x1b[2J\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\n")
+
+	input := make(chan []byte)
+	go func() {
+		for {
+			buf := make([]byte, 64)
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(input)
+				return
+			}
+			input <- buf[:n]
+		}
+	}()
+
+	perFrame := opts.Speed / uint64(time.Second/FRAME)
+	if perFrame == 0 {
+		perFrame = 1
+	}
+	ticker := time.NewTicker(FRAME)
+	defer ticker.Stop()
+	key, pressed := uint16(0), time.Time{}
+	last := ""
+	for {
+		select {
+		case buf, ok := <-input:
+			if !ok {
+				return nil
+			}
+			for len(buf) > 0 {
+				code, n := DecodeKey(buf)
+				buf = buf[n:]
+				if code == KEY_QUIT {
+					return nil
+				}
+				if code != 0 {
+					key, pressed = code, time.Now()
+					m.SetKey(key)
+				}
+			}
+		case <-ticker.C:
+			if key != 0 && time.Since(pressed) > KEY_HOLD {
+				key = 0
+				m.SetKey(0)
+			}
+			reason, err := m.Run(perFrame)
+			if err != nil {
+				return err
+			}
+			frame, _ := Render(ram, opts.Mode, opts.Scale)
+			status := fmt.Sprintf("key %-3d %-8s Ctrl-C to quit", key, reason)
+			if frame+status != last {
+				fmt.Print("\x1b[H" + strings.ReplaceAll(frame, "\n", "\r\n") + status + "\x1b[K")
+				last = frame + status
+			}
+		}
+	}
+}
+
+// rawMode 用 stty 关闭终端的行缓冲、回显和信号键，返回恢复原来设置的函数
+func rawMode() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("the screen needs a terminal: %v", err)
+	}
+	if _, err := stty("-icanon", "-echo", "-isig", "-ixon", "min", "1", "time", "0"); err != nil {
+		return nil, fmt.Errorf("the screen needs a terminal: %v", err)
+	}
+	return func() { stty(strings.TrimSpace(saved)) }, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
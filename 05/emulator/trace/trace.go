@@ -0,0 +1,284 @@
+// Package trace 记录 CPU 模拟器每个周期的 PC、A、D 和内存写入，并能向前和向后重放。
+//
+// 文件以 MAGIC 开头，后面是 flate 压缩的数据：先是开始时的 A、D、PC、周期数和整个 RAM，然后每个周期一条记录。
+// 记录的第一个和最后一个字节都是 flags，中间按 flags 依次是 PC、A、D 的异或差值，以及写入的地址和值的异或差值，
+// 都是小端的 16 位数。异或差值使同一条记录既能向前也能向后应用，首尾的 flags 使记录可以从后往前解析。
+// PC 没有跳转时不记录，向前是 +1，向后是 -1。键盘等外部写入单独记为 TRACE_POKE，不占周期
+package trace
+
+import (
+	"bufio"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"hongkuancn/nand2tetris/emulator/cpu"
+)
+
+const MAGIC = "HACKTRC1"
+
+// 记录中 flags 的位
+const (
+	TRACE_JUMP  = 1 << 0
+	TRACE_A     = 1 << 1
+	TRACE_D     = 1 << 2
+	TRACE_WRITE = 1 << 3
+	TRACE_POKE  = 1 << 4
+)
+
+// size 返回 flags 对应的记录长度
+func size(flags byte) int {
+	n := 2
+	for _, bit := range []byte{TRACE_JUMP, TRACE_A, TRACE_D} {
+		if flags&bit != 0 {
+			n += 2
+		}
+	}
+	if flags&(TRACE_WRITE|TRACE_POKE) != 0 {
+		n += 4
+	}
+	return n
+}
+
+// Recorder 执行程序并把每个周期写入 trace，实现了 screen.Machine
+type Recorder struct {
+	CPU *cpu.CPU
+
+	out    *bufio.Writer
+	flate  *flate.Writer
+	record []byte
+}
+
+// NewRecorder 写入 c 的当前状态作为 trace 的开始
+func NewRecorder(c *cpu.CPU, w io.Writer) (*Recorder, error) {
+	if _, err := io.WriteString(w, MAGIC); err != nil {
+		return nil, err
+	}
+	fw, err := flate.NewWriter(w, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{CPU: c, flate: fw, out: bufio.NewWriter(fw), record: make([]byte, 0, 16)}
+	header := []any{c.A, c.D, c.PC, c.Cycles, c.RAM}
+	for _, value := range header {
+		if err := binary.Write(r.out, binary.LittleEndian, value); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *Recorder) add(value uint16) {
+	r.record = binary.LittleEndian.AppendUint16(r.record, value)
+}
+
+func (r *Recorder) write(flags byte) error {
+	r.record[0] = flags
+	r.record = append(r.record, flags)
+	_, err := r.out.Write(r.record)
+	return err
+}
+
+// Step 执行一条指令并记录它改变了什么
+func (r *Recorder) Step() error {
+	c := r.CPU
+	pc, a, d := c.PC, c.A, c.D
+	address := c.A & cpu.ADDRESS_MASK
+	old := c.RAM[address]
+	if err := c.Step(); err != nil {
+		return err
+	}
+	flags := byte(0)
+	r.record = append(r.record[:0], 0)
+	if c.PC != pc+1 {
+		flags |= TRACE_JUMP
+		r.add(c.PC ^ pc)
+	}
+	if c.A != a {
+		flags |= TRACE_A
+		r.add(c.A ^ a)
+	}
+	if c.D != d {
+		flags |= TRACE_D
+		r.add(c.D ^ d)
+	}
+	if c.RAM[address] != old {
+		flags |= TRACE_WRITE
+		r.add(address)
+		r.add(c.RAM[address] ^ old)
+	}
+	return r.write(flags)
+}
+
+// Run 执行最多 budget 条指令，同 cpu.CPU.Run
+func (r *Recorder) Run(budget uint64) (string, error) {
+	for i := uint64(0); i < budget && !r.CPU.Halted(); i++ {
+		if err := r.Step(); err != nil {
+			return "", err
+		}
+	}
+	if r.CPU.Halted() {
+		return cpu.HALT_LOOP, nil
+	}
+	return cpu.HALT_BUDGET, nil
+}
+
+// SetKey 设置键盘寄存器，并记为一次外部写入
+func (r *Recorder) SetKey(key uint16) {
+	old := r.CPU.RAM[cpu.KBD]
+	r.CPU.SetKey(key)
+	if key == old {
+		return
+	}
+	r.record = append(r.record[:0], 0)
+	r.add(cpu.KBD)
+	r.add(key ^ old)
+	// 写入出错时会在 Close 中再次出现
+	r.write(TRACE_POKE)
+}
+
+// Close 写完 trace，不关闭底层的文件
+func (r *Recorder) Close() error {
+	if err := r.out.Flush(); err != nil {
+		return err
+	}
+	return r.flate.Close()
+}
+
+// Replay 在 CPU 上向前或向后重放 trace。重放只改变寄存器、RAM 和周期数，不执行指令，
+// 所以 CPU 中装入的程序只用来显示
+type Replay struct {
+	CPU *cpu.CPU
+	// Start 是 trace 开始时的周期数，Cycles 是 trace 中的周期数
+	Start  uint64
+	Cycles uint64
+
+	data []byte
+	pos  int
+}
+
+// Open 读取 trace 文件，把 c 设置为 trace 开始时的状态
+func Open(file string, c *cpu.CPU) (*Replay, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	magic := make([]byte, len(MAGIC))
+	if _, err := io.ReadFull(f, magic); err != nil || string(magic) != MAGIC {
+		return nil, fmt.Errorf("%s: not a trace file", file)
+	}
+	data, err := io.ReadAll(flate.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	header := 2*3 + 8 + 2*cpu.RAM_SIZE
+	if len(data) < header {
+		return nil, fmt.Errorf("%s: trace is truncated", file)
+	}
+	c.A = binary.LittleEndian.Uint16(data[0:])
+	c.D = binary.LittleEndian.Uint16(data[2:])
+	c.PC = binary.LittleEndian.Uint16(data[4:])
+	c.Cycles = binary.LittleEndian.Uint64(data[6:])
+	for i := range c.RAM {
+		c.RAM[i] = binary.LittleEndian.Uint16(data[14+2*i:])
+	}
+	r := &Replay{CPU: c, Start: c.Cycles, data: data[header:]}
+	// 检查每条记录都是完整的、写入的地址都在 RAM 中，之后向前和向后解析时就不用再检查
+	for pos := 0; pos < len(r.data); {
+		flags := r.data[pos]
+		n := size(flags)
+		if pos+n > len(r.data) || r.data[pos+n-1] != flags || !validFlags(flags) {
+			return nil, fmt.Errorf("%s: trace is corrupt at byte %d", file, pos)
+		}
+		if flags&(TRACE_WRITE|TRACE_POKE) != 0 {
+			// 地址和值是记录的最后两个字段
+			address := binary.LittleEndian.Uint16(r.data[pos+n-5:])
+			if address >= cpu.RAM_SIZE {
+				return nil, fmt.Errorf("%s: trace is corrupt at byte %d: address %d is outside RAM", file, pos, address)
+			}
+		}
+		if r.data[pos]&TRACE_POKE == 0 {
+			r.Cycles += 1
+		}
+		pos += n
+	}
+	return r, nil
+}
+
+// validFlags 判断 flags 是否只含已知的位，外部写入的记录只有 TRACE_POKE
+func validFlags(flags byte) bool {
+	if flags&TRACE_POKE != 0 {
+		return flags == TRACE_POKE
+	}
+	return flags&^(TRACE_JUMP|TRACE_A|TRACE_D|TRACE_WRITE) == 0
+}
+
+// apply 应用 pos 处的一条记录，向前和向后只有 PC 和周期数的处理不同
+func (r *Replay) apply(pos int, forward bool) {
+	c := r.CPU
+	flags := r.data[pos]
+	fields := r.data[pos+1:]
+	next := func() uint16 {
+		value := binary.LittleEndian.Uint16(fields)
+		fields = fields[2:]
+		return value
+	}
+	if flags&TRACE_POKE != 0 {
+		address := next()
+		c.RAM[address] ^= next()
+		return
+	}
+	if flags&TRACE_JUMP != 0 {
+		c.PC ^= next()
+	} else if forward {
+		c.PC += 1
+	} else {
+		c.PC -= 1
+	}
+	if flags&TRACE_A != 0 {
+		c.A ^= next()
+	}
+	if flags&TRACE_D != 0 {
+		c.D ^= next()
+	}
+	if flags&TRACE_WRITE != 0 {
+		address := next()
+		c.RAM[address] ^= next()
+	}
+	if forward {
+		c.Cycles += 1
+	} else {
+		c.Cycles -= 1
+	}
+}
+
+// Step 向前重放一个周期（连同它之前的外部写入），到了 trace 的末尾时返回 false
+func (r *Replay) Step() bool {
+	for r.pos < len(r.data) && r.data[r.pos]&TRACE_POKE != 0 {
+		r.apply(r.pos, true)
+		r.pos += size(r.data[r.pos])
+	}
+	if r.pos >= len(r.data) {
+		return false
+	}
+	r.apply(r.pos, true)
+	r.pos += size(r.data[r.pos])
+	return true
+}
+
+// Back 向后退一个周期（连同它之前的外部写入），到了 trace 的开头时返回 false
+func (r *Replay) Back() bool {
+	if r.pos == 0 {
+		return false
+	}
+	r.pos -= size(r.data[r.pos-1])
+	r.apply(r.pos, false)
+	for r.pos > 0 && r.data[r.pos-1]&TRACE_POKE != 0 {
+		r.pos -= size(r.data[r.pos-1])
+		r.apply(r.pos, false)
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package trace
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hongkuancn/nand2tetris/assembler/asm"
+	"hongkuancn/nand2tetris/emulator/cpu"
+)
+
+// writeTrace 写一个从全 0 的状态开始、包含 records 的 trace 文件
+func writeTrace(t *testing.T, records ...[]byte) string {
+	buf := bytes.Buffer{}
+	buf.WriteString(MAGIC)
+	fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(make([]byte, 2*3+8+2*cpu.RAM_SIZE))
+	for _, record := range records {
+		fw.Write(record)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "t.trace")
+	if err := os.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// record 按 flags 和字段拼出一条记录
+func record(flags byte, fields ...uint16) []byte {
+	res := []byte{flags}
+	for _, field := range fields {
+		res = binary.LittleEndian.AppendUint16(res, field)
+	}
+	return append(res, flags)
+}
+
+// TestRecordReplay 记录一段程序，向前重放到末尾和原来的状态相同，再向后退回开始时的状态
+func TestRecordReplay(t *testing.T) {
+	// RAM[16] 从 0 数到 5，然后停在死循环中
+	src := "@16\nM=0\n(LOOP)\n@16\nM=M+1\nD=M\n@5\nD=D-A\n@LOOP\nD;JLT\n(END)\n@END\n0;JMP\n"
+	prog, diags := asm.Assemble(strings.NewReader(src), asm.Options{Name: "count.asm"})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	c := cpu.NewCPU()
+	if err := c.Load(prog.Words()); err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.Buffer{}
+	r, err := NewRecorder(c, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Run(20)
+	r.SetKey('k')
+	if _, err := r.Run(1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if c.RAM[16] != 5 {
+		t.Fatalf("RAM[16] is %d, want 5", c.RAM[16])
+	}
+	file := filepath.Join(t.TempDir(), "count.trace")
+	if err := os.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := cpu.NewCPU()
+	replay, err := Open(file, replayed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Cycles != c.Cycles {
+		t.Fatalf("trace has %d cycles, want %d", replay.Cycles, c.Cycles)
+	}
+	for replay.Step() {
+	}
+	if replayed.A != c.A || replayed.D != c.D || replayed.PC != c.PC || replayed.Cycles != c.Cycles || replayed.RAM != c.RAM {
+		t.Fatalf("replayed to A=%d D=%d PC=%d cycles=%d, want A=%d D=%d PC=%d cycles=%d",
+			replayed.A, replayed.D, replayed.PC, replayed.Cycles, c.A, c.D, c.PC, c.Cycles)
+	}
+	for replay.Back() {
+	}
+	if replayed.A != 0 || replayed.D != 0 || replayed.PC != 0 || replayed.Cycles != 0 || replayed.RAM != [cpu.RAM_SIZE]uint16{} {
+		t.Fatalf("replayed back to A=%d D=%d PC=%d cycles=%d, want all 0", replayed.A, replayed.D, replayed.PC, replayed.Cycles)
+	}
+}
+
+// TestOpenCorrupt 损坏的 trace 在 Open 时报错，而不是在重放时越界
+func TestOpenCorrupt(t *testing.T) {
+	tests := []struct {
+		records [][]byte
+		want    string
+	}{
+		{[][]byte{record(TRACE_WRITE, 0x8000, 1)}, "address 32768 is outside RAM"},
+		{[][]byte{record(TRACE_A, 1), record(TRACE_POKE, 0xffff, 1)}, "corrupt at byte 4: address 65535 is outside RAM"},
+		{[][]byte{record(TRACE_POKE|TRACE_JUMP, 0xffff, 1, 2)}, "corrupt at byte 0"},
+		{[][]byte{record(1<<5, 1)}, "corrupt at byte 0"},
+		{[][]byte{record(TRACE_A, 1)[:3]}, "corrupt at byte 0"},
+	}
+	for _, test := range tests {
+		_, err := Open(writeTrace(t, test.records...), cpu.NewCPU())
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: got %v, want %q", test.records, err, test.want)
+		}
+	}
+	if _, err := Open(writeTrace(t, record(TRACE_WRITE, 0x7fff, 1), record(TRACE_POKE, cpu.KBD, 'k')), cpu.NewCPU()); err != nil {
+		t.Errorf("valid trace: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package tst
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"hongkuancn/nand2tetris/emulator/cpu"
+)
+
+// CPUMachine 让测试脚本驱动 CPU 模拟器，变量有 RAM[n]、ROM[n]、PC、A、D
+type CPUMachine struct {
+	CPU *cpu.CPU
+}
+
+func NewCPUMachine() *CPUMachine {
+	return &CPUMachine{CPU: cpu.NewCPU()}
+}
+
+func (m *CPUMachine) Load(dir string, file string) error {
+	if file == "" {
+		return fmt.Errorf("the CPU emulator needs a .hack or .asm file")
+	}
+	words, err := cpu.ReadProgram(filepath.Join(dir, file))
+	if err != nil {
+		return err
+	}
+	return m.CPU.Load(words)
+}
+
+func (m *CPUMachine) Get(name string) (int, error) {
+	switch name {
+	case "PC":
+		return int(m.CPU.PC), nil
+	case "A", "ARegister", "ARegister[]":
+		return int(m.CPU.A), nil
+	case "D", "DRegister", "DRegister[]":
+		return int(m.CPU.D), nil
+	case "time":
+		return int(m.CPU.Cycles), nil
+	}
+	memory, address, err := indexed(name)
+	if err != nil {
+		return 0, err
+	}
+	switch memory {
+	case "RAM":
+		return int(m.CPU.RAM[address]), nil
+	case "ROM":
+		return int(m.CPU.ROM[address]), nil
+	}
+	return 0, fmt.Errorf("unknown variable %s", name)
+}
+
+func (m *CPUMachine) Set(name string, value int) error {
+	switch name {
+	case "PC":
+		m.CPU.PC = uint16(value) & cpu.ADDRESS_MASK
+	case "A", "ARegister", "ARegister[]":
+		m.CPU.A = uint16(value)
+	case "D", "DRegister", "DRegister[]":
+		m.CPU.D = uint16(value)
+	default:
+		memory, address, err := indexed(name)
+		if err != nil {
+			return err
+		}
+		switch memory {
+		case "RAM":
+			m.CPU.Poke(uint16(address), uint16(value))
+		case "ROM":
+			m.CPU.ROM[address] = uint16(value)
+		default:
+			return fmt.Errorf("unknown variable %s", name)
+		}
+	}
+	m.CPU.Resume()
+	return nil
+}
+
+// Step 执行一条指令。tick 和 tock 是一个时钟周期的前后两半，指令在 tock 时执行。
+// 程序执行完最后一条指令后 PC 所指的 ROM 是空的，这时什么也不做
+func (m *CPUMachine) Step(command string) error {
+	switch command {
+	case "tick":
+		return nil
+	case "tock", "ticktock":
+		if int(m.CPU.PC) >= m.CPU.Size {
+			return nil
+		}
+		return m.CPU.Step()
+	}
+	return fmt.Errorf("%s is not supported by the CPU emulator", command)
+}
+
+func (m *CPUMachine) Halted() bool {
+	return m.CPU.Halted() || int(m.CPU.PC) >= m.CPU.Size
+}
+
+// indexed 解析 RAM[16] 这样的变量，返回 RAM 和 16
+func indexed(name string) (string, int, error) {
+	open := strings.IndexByte(name, '[')
+	if open < 0 || !strings.HasSuffix(name, "]") {
+		return "", 0, fmt.Errorf("unknown variable %s", name)
+	}
+	index, err := strconv.Atoi(name[open+1 : len(name)-1])
+	if err != nil || index < 0 || index >= cpu.RAM_SIZE {
+		return "", 0, fmt.Errorf("invalid address in %s", name)
+	}
+	return name[:open], index, nil
+}
@@ -0,0 +1,109 @@
+package tst
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Column 是 output-list 中的一项，如 RAM[0]%D2.6.2：格式 D，左边 2 个空格，值占 6 个字符，右边 2 个空格
+type Column struct {
+	Name   string
+	Format byte
+	Left   int
+	Width  int
+	Right  int
+}
+
+// ParseColumn 解析 output-list 中的一项，省略格式时为 %B1.16.1
+func ParseColumn(spec string) (Column, error) {
+	name, format, ok := strings.Cut(spec, "%")
+	col := Column{Name: name, Format: 'B', Left: 1, Width: 16, Right: 1}
+	if !ok {
+		return col, nil
+	}
+	if len(format) < 1 || strings.IndexByte("BDXS", format[0]) < 0 {
+		return col, fmt.Errorf("invalid output format %q", spec)
+	}
+	col.Format = format[0]
+	parts := strings.Split(format[1:], ".")
+	if len(parts) != 3 {
+		return col, fmt.Errorf("invalid output format %q, expected like %%D1.6.1", spec)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return col, fmt.Errorf("invalid output format %q", spec)
+		}
+		numbers[i] = n
+	}
+	col.Left, col.Width, col.Right = numbers[0], numbers[1], numbers[2]
+	return col, nil
+}
+
+// Header 返回列名，居中放在整列的宽度中，太长时截断
+func (c Column) Header() string {
+	total := c.Left + c.Width + c.Right
+	name := c.Name
+	if len(name) > total {
+		name = name[:total]
+	}
+	left := (total - len(name)) / 2
+	return strings.Repeat(" ", left) + name + strings.Repeat(" ", total-len(name)-left)
+}
+
+// Value 按格式输出值：D 是右对齐的十进制，B 和 X 是补零的二进制和十六进制，S 是左对齐的字符串
+func (c Column) Value(value int, text string) string {
+	var s string
+	switch c.Format {
+	case 'D':
+		s = fmt.Sprintf("%*d", c.Width, int16(value))
+	case 'B':
+		s = fmt.Sprintf("%0*b", c.Width, uint16(value))
+	case 'X':
+		s = fmt.Sprintf("%0*X", c.Width, uint16(value))
+	case 'S':
+		s = fmt.Sprintf("%-*s", c.Width, text)
+	}
+	if len(s) > c.Width {
+		s = s[len(s)-c.Width:]
+	}
+	return strings.Repeat(" ", c.Left) + s + strings.Repeat(" ", c.Right)
+}
+
+// ParseValue 解析 set 的值：十进制，或 %D、%X、%B 开头的十进制、十六进制、二进制
+func ParseValue(text string) (int, error) {
+	base := 10
+	digits := text
+	if strings.HasPrefix(text, "%") && len(text) > 2 {
+		switch text[1] {
+		case 'D':
+		case 'X':
+			base = 16
+		case 'B':
+			base = 2
+		default:
+			return 0, fmt.Errorf("invalid value %q", text)
+		}
+		digits = text[2:]
+	}
+	value, err := strconv.ParseInt(digits, base, 32)
+	if err != nil || value < -32768 || value > 65535 {
+		return 0, fmt.Errorf("invalid value %q", text)
+	}
+	return int(value), nil
+}
+
+// compareLine 比较输出行和 .cmp 中的行，.cmp 中的 * 匹配任意字符
+func compareLine(got string, want string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := 0; i < len(got); i++ {
+		if want[i] != '*' && want[i] != got[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,319 @@
+package tst
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Machine 是测试脚本驱动的模拟器
+type Machine interface {
+	// Load 装入程序，file 是相对于脚本目录 dir 的文件名，为空时装入 dir 中的所有程序
+	Load(dir string, file string) error
+	// Get 和 Set 读写脚本中的变量，如 RAM[0]、PC、sp、local[1]
+	Get(name string) (int, error)
+	Set(name string, value int) error
+	// Step 执行一条单步命令，如 ticktock、vmstep
+	Step(command string) error
+	// Halted 判断程序是否已经停止，没有次数的 repeat 在停止时结束
+	Halted() bool
+}
+
+// ComparisonError 是输出和 .cmp 不一致的错误
+type ComparisonError struct {
+	File string
+	Line int
+	Got  string
+	Want string
+}
+
+func (e *ComparisonError) Error() string {
+	return fmt.Sprintf("comparison failure at %s line %d:\n  got:  %s\n  want: %s", e.File, e.Line, e.Got, e.Want)
+}
+
+// MAX_STEPS 是没有次数的 repeat 和 while 默认最多执行的循环次数
+const MAX_STEPS = 10000000
+
+// Runner 执行一个测试脚本
+type Runner struct {
+	// NewMachine 根据 load 的文件名创建模拟器，如 .hack 和 .asm 用 CPU 模拟器
+	NewMachine func(file string) (Machine, error)
+	// MaxSteps 限制没有次数的 repeat 和 while 的循环次数，为 0 时是 MAX_STEPS
+	MaxSteps int
+	// Echo 接收 echo 命令的输出
+	Echo io.Writer
+
+	dir     string
+	machine Machine
+	columns []Column
+	outFile string
+	out     bytes.Buffer
+	cmpFile string
+	cmp     []string
+	lines   int
+}
+
+func NewRunner() *Runner {
+	return &Runner{NewMachine: DefaultMachine, Echo: io.Discard}
+}
+
+// DefaultMachine 根据文件扩展名选择模拟器：.hack 和 .asm 用 CPU 模拟器，.vm 和空（整个目录）用 VM 模拟器
+func DefaultMachine(file string) (Machine, error) {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".hack", ".asm":
+		return NewCPUMachine(), nil
+	case ".vm", "":
+		return NewVMMachine(), nil
+	}
+	return nil, fmt.Errorf("cannot load %q: expected a .hack, .asm or .vm file", file)
+}
+
+// Run 执行脚本文件，.out 写在脚本所在的目录中。输出和 .cmp 不一致时返回 *ComparisonError
+func (r *Runner) Run(file string) error {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	commands, err := Parse(string(src))
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	r.dir = filepath.Dir(file)
+	r.machine, r.columns, r.outFile, r.cmpFile, r.cmp, r.lines = nil, nil, "", "", nil, 0
+	r.out.Reset()
+
+	err = r.exec(commands)
+	if r.outFile != "" {
+		if werr := os.WriteFile(r.outFile, r.out.Bytes(), 0644); err == nil {
+			err = werr
+		}
+	}
+	if _, ok := err.(*ComparisonError); !ok && err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return err
+}
+
+func (r *Runner) exec(commands []Command) error {
+	for _, cmd := range commands {
+		err := r.execOne(cmd)
+		if _, ok := err.(*ComparisonError); ok {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %s: %w", cmd.Line, cmd.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) execOne(cmd Command) error {
+	switch cmd.Name {
+	case "load":
+		return r.load(cmd.Args)
+	case "output-file":
+		if len(cmd.Args) != 1 {
+			return fmt.Errorf("expected a file name")
+		}
+		r.outFile = filepath.Join(r.dir, cmd.Args[0])
+		return nil
+	case "compare-to":
+		if len(cmd.Args) != 1 {
+			return fmt.Errorf("expected a file name")
+		}
+		content, err := os.ReadFile(filepath.Join(r.dir, cmd.Args[0]))
+		if err != nil {
+			return err
+		}
+		r.cmpFile = cmd.Args[0]
+		r.cmp = strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+		return nil
+	case "output-list":
+		r.columns = r.columns[:0]
+		for _, arg := range cmd.Args {
+			col, err := ParseColumn(arg)
+			if err != nil {
+				return err
+			}
+			r.columns = append(r.columns, col)
+		}
+		headers := make([]string, len(r.columns))
+		for i, col := range r.columns {
+			headers[i] = col.Header()
+		}
+		return r.writeLine(headers)
+	case "output":
+		values := make([]string, len(r.columns))
+		for i, col := range r.columns {
+			machine, err := r.loaded()
+			if err != nil {
+				return err
+			}
+			value, err := machine.Get(col.Name)
+			if err != nil {
+				return err
+			}
+			values[i] = col.Value(value, fmt.Sprint(value))
+		}
+		return r.writeLine(values)
+	case "set":
+		if len(cmd.Args) != 2 {
+			return fmt.Errorf("expected a variable and a value")
+		}
+		value, err := ParseValue(cmd.Args[1])
+		if err != nil {
+			return err
+		}
+		machine, err := r.loaded()
+		if err != nil {
+			return err
+		}
+		return machine.Set(cmd.Args[0], value)
+	case "repeat":
+		return r.repeat(cmd)
+	case "while":
+		return r.while(cmd)
+	case "echo":
+		fmt.Fprintln(r.Echo, strings.Join(cmd.Args, " "))
+		return nil
+	case "clear-echo", "breakpoint", "clear-breakpoints":
+		return nil
+	case "tick", "tock", "ticktock", "vmstep":
+		machine, err := r.loaded()
+		if err != nil {
+			return err
+		}
+		return machine.Step(cmd.Name)
+	}
+	return fmt.Errorf("unknown command")
+}
+
+func (r *Runner) load(args []string) error {
+	file := ""
+	if len(args) > 0 {
+		file = args[0]
+	}
+	machine, err := r.NewMachine(file)
+	if err != nil {
+		return err
+	}
+	r.machine = machine
+	return machine.Load(r.dir, file)
+}
+
+func (r *Runner) loaded() (Machine, error) {
+	if r.machine == nil {
+		return nil, fmt.Errorf("no program loaded")
+	}
+	return r.machine, nil
+}
+
+// writeLine 输出一行并和 .cmp 中对应的行比较
+func (r *Runner) writeLine(columns []string) error {
+	line := "|" + strings.Join(columns, "|") + "|"
+	r.out.WriteString(line + "\n")
+	r.lines += 1
+	if r.cmp == nil {
+		return nil
+	}
+	want := ""
+	if r.lines <= len(r.cmp) {
+		want = strings.TrimRight(r.cmp[r.lines-1], " \t")
+	}
+	if !compareLine(strings.TrimRight(line, " \t"), want) {
+		return &ComparisonError{File: r.cmpFile, Line: r.lines, Got: line, Want: want}
+	}
+	return nil
+}
+
+// repeat 执行 repeat n { ... }，没有 n 时一直执行到程序停止
+func (r *Runner) repeat(cmd Command) error {
+	if len(cmd.Args) == 0 {
+		machine, err := r.loaded()
+		if err != nil {
+			return err
+		}
+		limit := r.maxSteps()
+		for i := 0; !machine.Halted(); i++ {
+			if i >= limit {
+				return fmt.Errorf("program did not halt within %d iterations", limit)
+			}
+			if err := r.exec(cmd.Body); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	n, err := strconv.Atoi(cmd.Args[0])
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid repeat count %q", cmd.Args[0])
+	}
+	for i := 0; i < n; i++ {
+		if err := r.exec(cmd.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// while 执行 while 变量 运算符 值 { ... }，运算符是 = <> < > <= >=
+func (r *Runner) while(cmd Command) error {
+	if len(cmd.Args) != 3 {
+		return fmt.Errorf("expected a condition like RAM[0] <> 0")
+	}
+	want, err := ParseValue(cmd.Args[2])
+	if err != nil {
+		return err
+	}
+	machine, err := r.loaded()
+	if err != nil {
+		return err
+	}
+	limit := r.maxSteps()
+	for i := 0; ; i++ {
+		value, err := machine.Get(cmd.Args[0])
+		if err != nil {
+			return err
+		}
+		ok, err := compare(int16(value), cmd.Args[1], int16(want))
+		if err != nil || !ok {
+			return err
+		}
+		if i >= limit {
+			return fmt.Errorf("condition %s %s %s still holds after %d iterations", cmd.Args[0], cmd.Args[1], cmd.Args[2], limit)
+		}
+		if err := r.exec(cmd.Body); err != nil {
+			return err
+		}
+	}
+}
+
+// maxSteps 返回没有次数的循环最多执行的次数
+func (r *Runner) maxSteps() int {
+	if r.MaxSteps == 0 {
+		return MAX_STEPS
+	}
+	return r.MaxSteps
+}
+
+func compare(x int16, op string, y int16) (bool, error) {
+	switch op {
+	case "=":
+		return x == y, nil
+	case "<>":
+		return x != y, nil
+	case "<":
+		return x < y, nil
+	case ">":
+		return x > y, nil
+	case "<=":
+		return x <= y, nil
+	case ">=":
+		return x >= y, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", op)
+}
@@ -0,0 +1,95 @@
+package tst
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ROOT 是仓库的根目录，go test 在包所在的目录中执行
+const ROOT = "../../.."
+
+// TestCourseScripts 执行 04、07、08 中所有的 .tst 脚本，输出和 .cmp 不一致时失败。
+// 04/fill/Fill.tst 需要手动按键，不在其中；07、08 中不带 VME 的脚本装入翻译器生成的 .asm，
+// 这些 .asm 由 08/translator 生成，放在 testdata 中
+func TestCourseScripts(t *testing.T) {
+	scripts := make([]string, 0)
+	for _, dir := range []string{"04", "07", "08"} {
+		err := filepath.WalkDir(filepath.Join(ROOT, dir), func(path string, d fs.DirEntry, err error) error {
+			if err == nil && strings.HasSuffix(path, ".tst") && filepath.Base(path) != "Fill.tst" {
+				scripts = append(scripts, path)
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(scripts) == 0 {
+		t.Fatal("no .tst scripts found")
+	}
+
+	for _, script := range scripts {
+		name, _ := filepath.Rel(ROOT, script)
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			// 在临时目录中的副本上执行，.out 不写进仓库
+			dir := t.TempDir()
+			copyFiles(t, filepath.Dir(script), dir)
+			if translated(script) {
+				asm := strings.TrimSuffix(filepath.Base(script), ".tst") + ".asm"
+				copyFile(t, filepath.Join("testdata", asm), filepath.Join(dir, asm))
+			}
+			if err := NewRunner().Run(filepath.Join(dir, filepath.Base(script))); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// translated 判断脚本是否装入翻译器生成的 .asm，即 07、08 中不带 VME 的脚本
+func translated(script string) bool {
+	name, _ := filepath.Rel(ROOT, script)
+	return !strings.HasPrefix(filepath.ToSlash(name), "04/") && !strings.HasSuffix(script, "VME.tst")
+}
+
+// copyFiles 把 from 目录中的文件（不含子目录）复制到 to
+func copyFiles(t *testing.T, from string, to string) {
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			copyFile(t, filepath.Join(from, entry.Name()), filepath.Join(to, entry.Name()))
+		}
+	}
+}
+
+func copyFile(t *testing.T, from string, to string) {
+	t.Helper()
+	content, err := os.ReadFile(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(to, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWhileLimit 条件一直成立的 while 在 MaxSteps 次后报错，而不是一直执行
+func TestWhileLimit(t *testing.T) {
+	dir := t.TempDir()
+	copyFile(t, filepath.Join("testdata", "SimpleAdd.asm"), filepath.Join(dir, "SimpleAdd.asm"))
+	script := filepath.Join(dir, "Loop.tst")
+	if err := os.WriteFile(script, []byte("load SimpleAdd.asm;\nwhile RAM[1] = 0 {\n\tticktock;\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner := NewRunner()
+	runner.MaxSteps = 1000
+	err := runner.Run(script)
+	if err == nil || !strings.Contains(err.Error(), "still holds after 1000 iterations") {
+		t.Fatalf("got %v, want an iteration limit error", err)
+	}
+}
@@ -0,0 +1,139 @@
+// Package tst 解释 nand2tetris 的测试脚本（.tst），驱动 CPU 或 VM 模拟器运行，
+// 把 output-list 指定的值写入 .out，并逐行和 .cmp 比较
+package tst
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Command 是脚本中的一条命令，repeat 和 while 的循环体在 Body 中
+type Command struct {
+	Name string
+	Args []string
+	Body []Command
+	Line int
+}
+
+type token struct {
+	text string
+	line int
+	// quoted 表示 text 来自带引号的字符串，如 echo "..."
+	quoted bool
+}
+
+// Parse 解析测试脚本。命令用 , 或 ; 分隔，repeat n { ... } 和 while cond { ... } 可以嵌套
+func Parse(src string) ([]Command, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &scriptParser{tokens: tokens}
+	commands, err := p.block(false)
+	if err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+// tokenize 把脚本切分为单词、字符串和 , ; { } 这些分隔符，去掉 // 和 /* */ 注释
+func tokenize(src string) ([]token, error) {
+	tokens := make([]token, 0)
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line += 1
+			i += 1
+		case c == ' ' || c == '\t' || c == '\r':
+			i += 1
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i += 1
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"':
+			end := strings.IndexByte(src[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			tokens = append(tokens, token{text: src[i+1 : i+1+end], line: line, quoted: true})
+			line += strings.Count(src[i+1:i+1+end], "\n")
+			i += end + 2
+		case strings.IndexByte(",;{}!", c) >= 0:
+			tokens = append(tokens, token{text: string(c), line: line})
+			i += 1
+		default:
+			start := i
+			for i < len(src) && strings.IndexByte(" \t\r\n,;{}!\"", src[i]) < 0 && !strings.HasPrefix(src[i:], "//") {
+				i += 1
+			}
+			tokens = append(tokens, token{text: src[start:i], line: line})
+		}
+	}
+	return tokens, nil
+}
+
+type scriptParser struct {
+	tokens []token
+	pos    int
+}
+
+func isSeparator(t token) bool {
+	return !t.quoted && len(t.text) == 1 && strings.Contains(",;{}!", t.text)
+}
+
+// block 解析一串命令，inner 为 true 时遇到 } 结束
+func (p *scriptParser) block(inner bool) ([]Command, error) {
+	commands := make([]Command, 0)
+	for p.pos < len(p.tokens) {
+		t := p.tokens[p.pos]
+		if isSeparator(t) && t.text == "}" {
+			if !inner {
+				return nil, fmt.Errorf("line %d: unexpected }", t.line)
+			}
+			p.pos += 1
+			return commands, nil
+		}
+		if isSeparator(t) {
+			// 多余的分隔符，如 repeat 的 } 后面的 ;
+			p.pos += 1
+			continue
+		}
+
+		cmd := Command{Name: t.text, Line: t.line}
+		p.pos += 1
+		for p.pos < len(p.tokens) && !isSeparator(p.tokens[p.pos]) {
+			cmd.Args = append(cmd.Args, p.tokens[p.pos].text)
+			p.pos += 1
+		}
+		if cmd.Name != "repeat" && cmd.Name != "while" {
+			if p.pos < len(p.tokens) && p.tokens[p.pos].text != "}" {
+				p.pos += 1
+			}
+			commands = append(commands, cmd)
+			continue
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].text != "{" {
+			return nil, fmt.Errorf("line %d: expected { after %s", cmd.Line, cmd.Name)
+		}
+		p.pos += 1
+		body, err := p.block(true)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Body = body
+		commands = append(commands, cmd)
+	}
+	if inner {
+		return nil, fmt.Errorf("missing }")
+	}
+	return commands, nil
+}
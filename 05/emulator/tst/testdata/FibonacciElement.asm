@@ -0,0 +1,527 @@
+@256
+D=A
+@SP
+M=D
+@Sys.boot$ret.0
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local
+@LCL
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push arg
+@ARG
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push this
+@THIS
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push that
+@THAT
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// arg = sp-5-args
+@0
+D=A
+@5
+D=A+D
+@SP
+D=M-D
+@ARG
+M=D
+// local=sp
+@SP
+D=M
+@LCL
+M=D
+// goto f
+@Sys.init
+0;JMP
+(Sys.boot$ret.0)
+// function Main.fibonacci 0
+(Main.fibonacci)
+@0
+D=A
+(Main.fibonacci$push)
+@Main.fibonacci$endpush
+D;JEQ
+@SP
+A=M
+M=0
+@SP
+M=M+1
+D=D-1
+@Main.fibonacci$push
+0;JMP
+(Main.fibonacci$endpush)
+// push argument 0
+@0
+D=A
+@ARG
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push constant 2
+@2
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// lt
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+D=M-D
+@$TRUE$0
+D;JLT
+@SP
+A=M
+M=0
+@$END$0
+0;JMP
+($TRUE$0)
+@SP
+A=M
+M=-1
+($END$0)
+@SP
+M=M+1
+// if-goto N_LT_2
+@SP
+M=M-1
+A=M
+D=M
+@Main.fibonacci$N_LT_2
+D;JNE
+// goto N_GE_2
+@Main.fibonacci$N_GE_2
+D;JMP
+// label N_LT_2               // if n < 2 returns n
+(Main.fibonacci$N_LT_2)
+// push argument 0
+@0
+D=A
+@ARG
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// return
+@LCL
+D=M
+@R15
+M=D
+// returnAddr=*(frame-5)
+@5
+D=A
+@R15
+A=M-D
+D=M
+@R14
+M=D
+@SP
+M=M-1
+A=M
+D=M
+// *ARG=pop()
+@ARG
+A=M
+M=D
+// SP=ARG+1
+@ARG
+D=M
+@SP
+M=D+1
+// pop THAT
+@R15
+AM=M-1
+D=M
+@THAT
+M=D
+// pop THIS
+@R15
+AM=M-1
+D=M
+@THIS
+M=D
+// pop ARG
+@R15
+AM=M-1
+D=M
+@ARG
+M=D
+// pop LCL
+@R15
+AM=M-1
+D=M
+@LCL
+M=D
+@R14
+A=M
+0;JMP
+// label N_GE_2               // if n >= 2 returns fib(n - 2) + fib(n - 1)
+(Main.fibonacci$N_GE_2)
+// push argument 0
+@0
+D=A
+@ARG
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push constant 2
+@2
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// sub
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+M=M-D
+@SP
+M=M+1
+// call Main.fibonacci 1  // computes fib(n - 2)
+@Main.fibonacci$ret.0
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local
+@LCL
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push arg
+@ARG
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push this
+@THIS
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push that
+@THAT
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// arg = sp-5-args
+@1
+D=A
+@5
+D=A+D
+@SP
+D=M-D
+@ARG
+M=D
+// local=sp
+@SP
+D=M
+@LCL
+M=D
+// goto f
+@Main.fibonacci
+0;JMP
+(Main.fibonacci$ret.0)
+// push argument 0
+@0
+D=A
+@ARG
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push constant 1
+@1
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// sub
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+M=M-D
+@SP
+M=M+1
+// call Main.fibonacci 1  // computes fib(n - 1)
+@Main.fibonacci$ret.1
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local
+@LCL
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push arg
+@ARG
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push this
+@THIS
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push that
+@THAT
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// arg = sp-5-args
+@1
+D=A
+@5
+D=A+D
+@SP
+D=M-D
+@ARG
+M=D
+// local=sp
+@SP
+D=M
+@LCL
+M=D
+// goto f
+@Main.fibonacci
+0;JMP
+(Main.fibonacci$ret.1)
+// add                    // returns fib(n - 1) + fib(n - 2)
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+M=M+D
+@SP
+M=M+1
+// return
+@LCL
+D=M
+@R15
+M=D
+// returnAddr=*(frame-5)
+@5
+D=A
+@R15
+A=M-D
+D=M
+@R14
+M=D
+@SP
+M=M-1
+A=M
+D=M
+// *ARG=pop()
+@ARG
+A=M
+M=D
+// SP=ARG+1
+@ARG
+D=M
+@SP
+M=D+1
+// pop THAT
+@R15
+AM=M-1
+D=M
+@THAT
+M=D
+// pop THIS
+@R15
+AM=M-1
+D=M
+@THIS
+M=D
+// pop ARG
+@R15
+AM=M-1
+D=M
+@ARG
+M=D
+// pop LCL
+@R15
+AM=M-1
+D=M
+@LCL
+M=D
+@R14
+A=M
+0;JMP
+// function Sys.init 0
+(Sys.init)
+@0
+D=A
+(Sys.init$push)
+@Sys.init$endpush
+D;JEQ
+@SP
+A=M
+M=0
+@SP
+M=M+1
+D=D-1
+@Sys.init$push
+0;JMP
+(Sys.init$endpush)
+// push constant 4
+@4
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// call Main.fibonacci 1   // computes the 4'th fibonacci element
+@Sys.init$ret.0
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local
+@LCL
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push arg
+@ARG
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push this
+@THIS
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push that
+@THAT
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// arg = sp-5-args
+@1
+D=A
+@5
+D=A+D
+@SP
+D=M-D
+@ARG
+M=D
+// local=sp
+@SP
+D=M
+@LCL
+M=D
+// goto f
+@Main.fibonacci
+0;JMP
+(Sys.init$ret.0)
+// label END
+(Sys.init$END)
+// goto END                // loops infinitely
+@Sys.init$END
+D;JMP
+(END)
+@END
+0;JMP
@@ -0,0 +1,692 @@
+@256
+D=A
+@SP
+M=D
+@Sys.boot$ret.0
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local
+@LCL
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push arg
+@ARG
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push this
+@THIS
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push that
+@THAT
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// arg = sp-5-args
+@0
+D=A
+@5
+D=A+D
+@SP
+D=M-D
+@ARG
+M=D
+// local=sp
+@SP
+D=M
+@LCL
+M=D
+// goto f
+@Sys.init
+0;JMP
+(Sys.boot$ret.0)
+// function Sys.init 0
+(Sys.init)
+@0
+D=A
+(Sys.init$push)
+@Sys.init$endpush
+D;JEQ
+@SP
+A=M
+M=0
+@SP
+M=M+1
+D=D-1
+@Sys.init$push
+0;JMP
+(Sys.init$endpush)
+// push constant 4000	// tests that THIS and THAT are handled correctly
+@4000
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// pop pointer 0
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@THIS
+M=D
+// push constant 5000
+@5000
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// pop pointer 1
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@THAT
+M=D
+// call Sys.main 0
+@Sys.init$ret.0
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local
+@LCL
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push arg
+@ARG
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push this
+@THIS
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push that
+@THAT
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// arg = sp-5-args
+@0
+D=A
+@5
+D=A+D
+@SP
+D=M-D
+@ARG
+M=D
+// local=sp
+@SP
+D=M
+@LCL
+M=D
+// goto f
+@Sys.main
+0;JMP
+(Sys.init$ret.0)
+// pop temp 1
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@1
+D=A
+@5
+D=A+D
+@R14
+M=D
+@R13
+D=M
+@R14
+A=M
+M=D
+// label LOOP
+(Sys.init$LOOP)
+// goto LOOP
+@Sys.init$LOOP
+D;JMP
+// function Sys.main 5
+(Sys.main)
+@5
+D=A
+(Sys.main$push)
+@Sys.main$endpush
+D;JEQ
+@SP
+A=M
+M=0
+@SP
+M=M+1
+D=D-1
+@Sys.main$push
+0;JMP
+(Sys.main$endpush)
+// push constant 4001
+@4001
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// pop pointer 0
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@THIS
+M=D
+// push constant 5001
+@5001
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// pop pointer 1
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@THAT
+M=D
+// push constant 200
+@200
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// pop local 1
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@1
+D=A
+@LCL
+D=M+D
+@R14
+M=D
+@R13
+D=M
+@R14
+A=M
+M=D
+// push constant 40
+@40
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// pop local 2
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@2
+D=A
+@LCL
+D=M+D
+@R14
+M=D
+@R13
+D=M
+@R14
+A=M
+M=D
+// push constant 6
+@6
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// pop local 3
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@3
+D=A
+@LCL
+D=M+D
+@R14
+M=D
+@R13
+D=M
+@R14
+A=M
+M=D
+// push constant 123
+@123
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// call Sys.add12 1
+@Sys.main$ret.0
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local
+@LCL
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push arg
+@ARG
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push this
+@THIS
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push that
+@THAT
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// arg = sp-5-args
+@1
+D=A
+@5
+D=A+D
+@SP
+D=M-D
+@ARG
+M=D
+// local=sp
+@SP
+D=M
+@LCL
+M=D
+// goto f
+@Sys.add12
+0;JMP
+(Sys.main$ret.0)
+// pop temp 0
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@0
+D=A
+@5
+D=A+D
+@R14
+M=D
+@R13
+D=M
+@R14
+A=M
+M=D
+// push local 0
+@0
+D=A
+@LCL
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local 1
+@1
+D=A
+@LCL
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local 2
+@2
+D=A
+@LCL
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local 3
+@3
+D=A
+@LCL
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local 4
+@4
+D=A
+@LCL
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// add
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+M=M+D
+@SP
+M=M+1
+// add
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+M=M+D
+@SP
+M=M+1
+// add
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+M=M+D
+@SP
+M=M+1
+// add
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+M=M+D
+@SP
+M=M+1
+// return
+@LCL
+D=M
+@R15
+M=D
+// returnAddr=*(frame-5)
+@5
+D=A
+@R15
+A=M-D
+D=M
+@R14
+M=D
+@SP
+M=M-1
+A=M
+D=M
+// *ARG=pop()
+@ARG
+A=M
+M=D
+// SP=ARG+1
+@ARG
+D=M
+@SP
+M=D+1
+// pop THAT
+@R15
+AM=M-1
+D=M
+@THAT
+M=D
+// pop THIS
+@R15
+AM=M-1
+D=M
+@THIS
+M=D
+// pop ARG
+@R15
+AM=M-1
+D=M
+@ARG
+M=D
+// pop LCL
+@R15
+AM=M-1
+D=M
+@LCL
+M=D
+@R14
+A=M
+0;JMP
+// function Sys.add12 0
+(Sys.add12)
+@0
+D=A
+(Sys.add12$push)
+@Sys.add12$endpush
+D;JEQ
+@SP
+A=M
+M=0
+@SP
+M=M+1
+D=D-1
+@Sys.add12$push
+0;JMP
+(Sys.add12$endpush)
+// push constant 4002
+@4002
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// pop pointer 0
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@THIS
+M=D
+// push constant 5002
+@5002
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// pop pointer 1
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@THAT
+M=D
+// push argument 0
+@0
+D=A
+@ARG
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push constant 12
+@12
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// add
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+M=M+D
+@SP
+M=M+1
+// return
+@LCL
+D=M
+@R15
+M=D
+// returnAddr=*(frame-5)
+@5
+D=A
+@R15
+A=M-D
+D=M
+@R14
+M=D
+@SP
+M=M-1
+A=M
+D=M
+// *ARG=pop()
+@ARG
+A=M
+M=D
+// SP=ARG+1
+@ARG
+D=M
+@SP
+M=D+1
+// pop THAT
+@R15
+AM=M-1
+D=M
+@THAT
+M=D
+// pop THIS
+@R15
+AM=M-1
+D=M
+@THIS
+M=D
+// pop ARG
+@R15
+AM=M-1
+D=M
+@ARG
+M=D
+// pop LCL
+@R15
+AM=M-1
+D=M
+@LCL
+M=D
+@R14
+A=M
+0;JMP
+(END)
+@END
+0;JMP
@@ -0,0 +1,156 @@
+// function SimpleFunction.test 2
+(SimpleFunction.test)
+@2
+D=A
+(SimpleFunction.test$push)
+@SimpleFunction.test$endpush
+D;JEQ
+@SP
+A=M
+M=0
+@SP
+M=M+1
+D=D-1
+@SimpleFunction.test$push
+0;JMP
+(SimpleFunction.test$endpush)
+// push local 0
+@0
+D=A
+@LCL
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local 1
+@1
+D=A
+@LCL
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// add
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+M=M+D
+@SP
+M=M+1
+// not
+@SP
+M=M-1
+A=M
+M=!M
+@SP
+M=M+1
+// push argument 0
+@0
+D=A
+@ARG
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// add
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+M=M+D
+@SP
+M=M+1
+// push argument 1
+@1
+D=A
+@ARG
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// sub
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+M=M-D
+@SP
+M=M+1
+// return
+@LCL
+D=M
+@R15
+M=D
+// returnAddr=*(frame-5)
+@5
+D=A
+@R15
+A=M-D
+D=M
+@R14
+M=D
+@SP
+M=M-1
+A=M
+D=M
+// *ARG=pop()
+@ARG
+A=M
+M=D
+// SP=ARG+1
+@ARG
+D=M
+@SP
+M=D+1
+// pop THAT
+@R15
+AM=M-1
+D=M
+@THAT
+M=D
+// pop THIS
+@R15
+AM=M-1
+D=M
+@THIS
+M=D
+// pop ARG
+@R15
+AM=M-1
+D=M
+@ARG
+M=D
+// pop LCL
+@R15
+AM=M-1
+D=M
+@LCL
+M=D
+@R14
+A=M
+0;JMP
+(END)
+@END
+0;JMP
@@ -0,0 +1,811 @@
+@256
+D=A
+@SP
+M=D
+@Sys.boot$ret.0
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local
+@LCL
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push arg
+@ARG
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push this
+@THIS
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push that
+@THAT
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// arg = sp-5-args
+@0
+D=A
+@5
+D=A+D
+@SP
+D=M-D
+@ARG
+M=D
+// local=sp
+@SP
+D=M
+@LCL
+M=D
+// goto f
+@Sys.init
+0;JMP
+(Sys.boot$ret.0)
+// function Class1.set 0
+(Class1.set)
+@0
+D=A
+(Class1.set$push)
+@Class1.set$endpush
+D;JEQ
+@SP
+A=M
+M=0
+@SP
+M=M+1
+D=D-1
+@Class1.set$push
+0;JMP
+(Class1.set$endpush)
+// push argument 0
+@0
+D=A
+@ARG
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// pop static 0
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@Class1.0
+M=D
+// push argument 1
+@1
+D=A
+@ARG
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// pop static 1
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@Class1.1
+M=D
+// push constant 0
+@0
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// return
+@LCL
+D=M
+@R15
+M=D
+// returnAddr=*(frame-5)
+@5
+D=A
+@R15
+A=M-D
+D=M
+@R14
+M=D
+@SP
+M=M-1
+A=M
+D=M
+// *ARG=pop()
+@ARG
+A=M
+M=D
+// SP=ARG+1
+@ARG
+D=M
+@SP
+M=D+1
+// pop THAT
+@R15
+AM=M-1
+D=M
+@THAT
+M=D
+// pop THIS
+@R15
+AM=M-1
+D=M
+@THIS
+M=D
+// pop ARG
+@R15
+AM=M-1
+D=M
+@ARG
+M=D
+// pop LCL
+@R15
+AM=M-1
+D=M
+@LCL
+M=D
+@R14
+A=M
+0;JMP
+// function Class1.get 0
+(Class1.get)
+@0
+D=A
+(Class1.get$push)
+@Class1.get$endpush
+D;JEQ
+@SP
+A=M
+M=0
+@SP
+M=M+1
+D=D-1
+@Class1.get$push
+0;JMP
+(Class1.get$endpush)
+// push static 0
+@Class1.0
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push static 1
+@Class1.1
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// sub
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+M=M-D
+@SP
+M=M+1
+// return
+@LCL
+D=M
+@R15
+M=D
+// returnAddr=*(frame-5)
+@5
+D=A
+@R15
+A=M-D
+D=M
+@R14
+M=D
+@SP
+M=M-1
+A=M
+D=M
+// *ARG=pop()
+@ARG
+A=M
+M=D
+// SP=ARG+1
+@ARG
+D=M
+@SP
+M=D+1
+// pop THAT
+@R15
+AM=M-1
+D=M
+@THAT
+M=D
+// pop THIS
+@R15
+AM=M-1
+D=M
+@THIS
+M=D
+// pop ARG
+@R15
+AM=M-1
+D=M
+@ARG
+M=D
+// pop LCL
+@R15
+AM=M-1
+D=M
+@LCL
+M=D
+@R14
+A=M
+0;JMP
+// function Class2.set 0
+(Class2.set)
+@0
+D=A
+(Class2.set$push)
+@Class2.set$endpush
+D;JEQ
+@SP
+A=M
+M=0
+@SP
+M=M+1
+D=D-1
+@Class2.set$push
+0;JMP
+(Class2.set$endpush)
+// push argument 0
+@0
+D=A
+@ARG
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// pop static 0
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@Class2.0
+M=D
+// push argument 1
+@1
+D=A
+@ARG
+A=M+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// pop static 1
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@Class2.1
+M=D
+// push constant 0
+@0
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// return
+@LCL
+D=M
+@R15
+M=D
+// returnAddr=*(frame-5)
+@5
+D=A
+@R15
+A=M-D
+D=M
+@R14
+M=D
+@SP
+M=M-1
+A=M
+D=M
+// *ARG=pop()
+@ARG
+A=M
+M=D
+// SP=ARG+1
+@ARG
+D=M
+@SP
+M=D+1
+// pop THAT
+@R15
+AM=M-1
+D=M
+@THAT
+M=D
+// pop THIS
+@R15
+AM=M-1
+D=M
+@THIS
+M=D
+// pop ARG
+@R15
+AM=M-1
+D=M
+@ARG
+M=D
+// pop LCL
+@R15
+AM=M-1
+D=M
+@LCL
+M=D
+@R14
+A=M
+0;JMP
+// function Class2.get 0
+(Class2.get)
+@0
+D=A
+(Class2.get$push)
+@Class2.get$endpush
+D;JEQ
+@SP
+A=M
+M=0
+@SP
+M=M+1
+D=D-1
+@Class2.get$push
+0;JMP
+(Class2.get$endpush)
+// push static 0
+@Class2.0
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push static 1
+@Class2.1
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// sub
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+M=M-D
+@SP
+M=M+1
+// return
+@LCL
+D=M
+@R15
+M=D
+// returnAddr=*(frame-5)
+@5
+D=A
+@R15
+A=M-D
+D=M
+@R14
+M=D
+@SP
+M=M-1
+A=M
+D=M
+// *ARG=pop()
+@ARG
+A=M
+M=D
+// SP=ARG+1
+@ARG
+D=M
+@SP
+M=D+1
+// pop THAT
+@R15
+AM=M-1
+D=M
+@THAT
+M=D
+// pop THIS
+@R15
+AM=M-1
+D=M
+@THIS
+M=D
+// pop ARG
+@R15
+AM=M-1
+D=M
+@ARG
+M=D
+// pop LCL
+@R15
+AM=M-1
+D=M
+@LCL
+M=D
+@R14
+A=M
+0;JMP
+// function Sys.init 0
+(Sys.init)
+@0
+D=A
+(Sys.init$push)
+@Sys.init$endpush
+D;JEQ
+@SP
+A=M
+M=0
+@SP
+M=M+1
+D=D-1
+@Sys.init$push
+0;JMP
+(Sys.init$endpush)
+// push constant 6
+@6
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push constant 8
+@8
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// call Class1.set 2
+@Sys.init$ret.0
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local
+@LCL
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push arg
+@ARG
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push this
+@THIS
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push that
+@THAT
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// arg = sp-5-args
+@2
+D=A
+@5
+D=A+D
+@SP
+D=M-D
+@ARG
+M=D
+// local=sp
+@SP
+D=M
+@LCL
+M=D
+// goto f
+@Class1.set
+0;JMP
+(Sys.init$ret.0)
+// pop temp 0 // dumps the return value
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@0
+D=A
+@5
+D=A+D
+@R14
+M=D
+@R13
+D=M
+@R14
+A=M
+M=D
+// push constant 23
+@23
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push constant 15
+@15
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// call Class2.set 2
+@Sys.init$ret.1
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local
+@LCL
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push arg
+@ARG
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push this
+@THIS
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push that
+@THAT
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// arg = sp-5-args
+@2
+D=A
+@5
+D=A+D
+@SP
+D=M-D
+@ARG
+M=D
+// local=sp
+@SP
+D=M
+@LCL
+M=D
+// goto f
+@Class2.set
+0;JMP
+(Sys.init$ret.1)
+// pop temp 0 // dumps the return value
+@SP
+M=M-1
+A=M
+D=M
+@R13
+M=D
+@0
+D=A
+@5
+D=A+D
+@R14
+M=D
+@R13
+D=M
+@R14
+A=M
+M=D
+// call Class1.get 0
+@Sys.init$ret.2
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local
+@LCL
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push arg
+@ARG
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push this
+@THIS
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push that
+@THAT
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// arg = sp-5-args
+@0
+D=A
+@5
+D=A+D
+@SP
+D=M-D
+@ARG
+M=D
+// local=sp
+@SP
+D=M
+@LCL
+M=D
+// goto f
+@Class1.get
+0;JMP
+(Sys.init$ret.2)
+// call Class2.get 0
+@Sys.init$ret.3
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push local
+@LCL
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push arg
+@ARG
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push this
+@THIS
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// push that
+@THAT
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+// arg = sp-5-args
+@0
+D=A
+@5
+D=A+D
+@SP
+D=M-D
+@ARG
+M=D
+// local=sp
+@SP
+D=M
+@LCL
+M=D
+// goto f
+@Class2.get
+0;JMP
+(Sys.init$ret.3)
+// label END
+(Sys.init$END)
+// goto END
+@Sys.init$END
+D;JMP
+(END)
+@END
+0;JMP
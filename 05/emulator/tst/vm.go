@@ -0,0 +1,90 @@
+package tst
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"hongkuancn/nand2tetris/emulator/vm"
+)
+
+// VMMachine 让测试脚本驱动 VM 模拟器。变量有 RAM[n]、sp、local、argument、this、that，
+// 以及段中的元素 local[i]、argument[i]、this[i]、that[i]、temp[i]
+type VMMachine struct {
+	VM *vm.VM
+}
+
+func NewVMMachine() *VMMachine {
+	return &VMMachine{}
+}
+
+// Load 装入一个 .vm 文件，file 为空时装入目录中的所有 .vm 文件
+func (m *VMMachine) Load(dir string, file string) error {
+	prog, err := vm.ReadProgram(filepath.Join(dir, file))
+	if err != nil {
+		return err
+	}
+	m.VM = vm.New(prog)
+	return nil
+}
+
+// pointers 是段名对应的基地址寄存器
+var pointers = map[string]int{"sp": vm.SP, "local": vm.LCL, "argument": vm.ARG, "this": vm.THIS, "that": vm.THAT}
+
+// address 返回变量的 RAM 地址
+func (m *VMMachine) address(name string) (int, error) {
+	if register, ok := pointers[name]; ok {
+		return register, nil
+	}
+	segment, index, err := indexed(name)
+	if err != nil {
+		return 0, err
+	}
+	switch segment {
+	case "RAM":
+		return index, nil
+	case "temp":
+		if index > 7 {
+			return 0, fmt.Errorf("invalid index in %s", name)
+		}
+		return vm.TEMP + index, nil
+	}
+	register, ok := pointers[segment]
+	if !ok || segment == "sp" {
+		return 0, fmt.Errorf("unknown variable %s", name)
+	}
+	return (int(m.VM.RAM[register]) + index) & 0x7fff, nil
+}
+
+func (m *VMMachine) Get(name string) (int, error) {
+	if m.VM == nil {
+		return 0, fmt.Errorf("no program loaded")
+	}
+	address, err := m.address(name)
+	if err != nil {
+		return 0, err
+	}
+	return int(m.VM.RAM[address]), nil
+}
+
+func (m *VMMachine) Set(name string, value int) error {
+	if m.VM == nil {
+		return fmt.Errorf("no program loaded")
+	}
+	address, err := m.address(name)
+	if err != nil {
+		return err
+	}
+	m.VM.Poke(address, uint16(value))
+	return nil
+}
+
+func (m *VMMachine) Step(command string) error {
+	if command != "vmstep" {
+		return fmt.Errorf("%s is not supported by the VM emulator", command)
+	}
+	return m.VM.Step()
+}
+
+func (m *VMMachine) Halted() bool {
+	return m.VM.Halted()
+}
@@ -0,0 +1,39 @@
+package vm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReadProgram 读取一个 .vm 文件，或者目录中的所有 .vm 文件（按文件名排序，和翻译器的顺序一致）
+func ReadProgram(path string) (*Program, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.vm"))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no .vm files in %s", path)
+		}
+		sort.Strings(files)
+	}
+
+	srcs := make([]Source, 0, len(files))
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		srcs = append(srcs, Source{Name: strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)), Reader: f})
+	}
+	return Load(srcs)
+}
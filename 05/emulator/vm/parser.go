@@ -0,0 +1,116 @@
+// Package vm 直接解释执行 .vm 文件，是 VM 翻译器的参考实现。
+// 内存布局和翻译后的汇编一致：SP、LCL、ARG、THIS、THAT 在 RAM[0..4]，temp 在 RAM[5..12]，static 从 16 开始，栈从 256 开始
+package vm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 命令类型，和 08/translator 的 Parser.commandType 一致
+const (
+	C_ARITHMETIC = "ARITHMETIC"
+	C_PUSH       = "PUSH"
+	C_POP        = "POP"
+	C_GOTO       = "GOTO"
+	C_IF         = "IF"
+	C_RETURN     = "RETURN"
+	C_FUNCTION   = "FUNCTION"
+	C_LABEL      = "LABEL"
+	C_CALL       = "CALL"
+)
+
+// Command 是一条 VM 命令。Arg1 是算术命令本身、段名、label 或函数名，Arg2 是下标、局部变量数或参数数
+type Command struct {
+	Type string
+	Arg1 string
+	Arg2 int
+	// File 是命令所在文件的类名（不含 .vm），决定 static 段；Line 从 1 开始
+	File string
+	Line int
+	Text string
+}
+
+func (c Command) String() string {
+	return c.Text
+}
+
+// Source 是一个 .vm 文件
+type Source struct {
+	Name   string
+	Reader io.Reader
+}
+
+// commandType 返回命令的类型，不认识的命令返回空串
+func commandType(name string) string {
+	switch name {
+	case "add", "sub", "neg", "eq", "gt", "lt", "and", "or", "not":
+		return C_ARITHMETIC
+	case "push":
+		return C_PUSH
+	case "pop":
+		return C_POP
+	case "goto":
+		return C_GOTO
+	case "if-goto":
+		return C_IF
+	case "call":
+		return C_CALL
+	case "function":
+		return C_FUNCTION
+	case "return":
+		return C_RETURN
+	case "label":
+		return C_LABEL
+	}
+	return ""
+}
+
+// Parse 解析一个 .vm 文件，class 是它的类名
+func Parse(r io.Reader, class string) ([]Command, error) {
+	commands := make([]Command, 0)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		cmd := Command{Type: commandType(fields[0]), File: class, Line: n, Text: strings.Join(fields, " ")}
+		want := 0
+		switch cmd.Type {
+		case "":
+			return nil, fmt.Errorf("%s.vm:%d: unknown command %q", class, n, fields[0])
+		case C_ARITHMETIC:
+			cmd.Arg1 = fields[0]
+		case C_GOTO, C_IF, C_LABEL:
+			want = 1
+		case C_PUSH, C_POP, C_FUNCTION, C_CALL:
+			want = 2
+		}
+		if len(fields)-1 != want {
+			return nil, fmt.Errorf("%s.vm:%d: %s expects %d arguments", class, n, fields[0], want)
+		}
+		if want >= 1 {
+			cmd.Arg1 = fields[1]
+		}
+		if want == 2 {
+			value, err := strconv.Atoi(fields[2])
+			if err != nil || value < 0 || value > 32767 {
+				return nil, fmt.Errorf("%s.vm:%d: invalid number %q", class, n, fields[2])
+			}
+			cmd.Arg2 = value
+		}
+		commands = append(commands, cmd)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s.vm: %v", class, err)
+	}
+	return commands, nil
+}
@@ -0,0 +1,394 @@
+package vm
+
+import (
+	"fmt"
+
+	"hongkuancn/nand2tetris/emulator/cpu"
+)
+
+// 段的基地址寄存器和固定区域
+const (
+	SP     = 0
+	LCL    = 1
+	ARG    = 2
+	THIS   = 3
+	THAT   = 4
+	TEMP   = 5
+	STATIC = 16
+	// STACK 是 Sys.init 引导时的栈底
+	STACK = 256
+)
+
+// Program 是装入的所有 .vm 文件，跳转目标和 static 地址在装入时已解析
+type Program struct {
+	Commands []Command
+	// Functions 是函数名对应的 function 命令的下标
+	Functions map[string]int
+	// Statics 是 static 变量 类名.下标 对应的 RAM 地址，按第一次出现的顺序从 16 开始分配，和汇编器分配变量的顺序一致
+	Statics map[string]int
+	// targets 是 goto、if-goto 和 call 命令跳转到的下标，其它命令为 -1
+	targets []int
+}
+
+// Load 解析并链接多个 .vm 文件。label 只在定义它的函数内可见，函数之外的 label 只在本文件内可见
+func Load(srcs []Source) (*Program, error) {
+	prog := &Program{Functions: make(map[string]int), Statics: make(map[string]int)}
+	labels := make(map[string]int)
+	scopes := make([]string, 0)
+	for _, src := range srcs {
+		commands, err := Parse(src.Reader, src.Name)
+		if err != nil {
+			return nil, err
+		}
+		scope := src.Name + ".vm"
+		for _, cmd := range commands {
+			if err := prog.check(cmd); err != nil {
+				return nil, err
+			}
+			switch cmd.Type {
+			case C_FUNCTION:
+				if _, ok := prog.Functions[cmd.Arg1]; ok {
+					return nil, fmt.Errorf("%s.vm:%d: function %s already defined", cmd.File, cmd.Line, cmd.Arg1)
+				}
+				scope = cmd.Arg1
+				prog.Functions[cmd.Arg1] = len(prog.Commands)
+			case C_LABEL:
+				key := scope + "$" + cmd.Arg1
+				if _, ok := labels[key]; ok {
+					return nil, fmt.Errorf("%s.vm:%d: label %s already defined in %s", cmd.File, cmd.Line, cmd.Arg1, scope)
+				}
+				labels[key] = len(prog.Commands)
+			}
+			scopes = append(scopes, scope)
+			prog.Commands = append(prog.Commands, cmd)
+		}
+	}
+
+	prog.targets = make([]int, len(prog.Commands))
+	for i, cmd := range prog.Commands {
+		prog.targets[i] = -1
+		switch cmd.Type {
+		case C_GOTO, C_IF:
+			target, ok := labels[scopes[i]+"$"+cmd.Arg1]
+			if !ok {
+				return nil, fmt.Errorf("%s.vm:%d: undefined label %s", cmd.File, cmd.Line, cmd.Arg1)
+			}
+			prog.targets[i] = target
+		case C_CALL:
+			target, ok := prog.Functions[cmd.Arg1]
+			if !ok {
+				return nil, fmt.Errorf("%s.vm:%d: undefined function %s", cmd.File, cmd.Line, cmd.Arg1)
+			}
+			prog.targets[i] = target
+		}
+	}
+	return prog, nil
+}
+
+// check 检查段名和下标，给 static 变量分配地址
+func (p *Program) check(cmd Command) error {
+	if cmd.Type != C_PUSH && cmd.Type != C_POP {
+		return nil
+	}
+	switch cmd.Arg1 {
+	case "local", "argument", "this", "that":
+		return nil
+	case "constant":
+		if cmd.Type == C_POP {
+			return fmt.Errorf("%s.vm:%d: cannot pop to constant", cmd.File, cmd.Line)
+		}
+		return nil
+	case "temp":
+		if cmd.Arg2 > 7 {
+			return fmt.Errorf("%s.vm:%d: temp index %d out of range 0-7", cmd.File, cmd.Line, cmd.Arg2)
+		}
+		return nil
+	case "pointer":
+		if cmd.Arg2 > 1 {
+			return fmt.Errorf("%s.vm:%d: pointer index %d out of range 0-1", cmd.File, cmd.Line, cmd.Arg2)
+		}
+		return nil
+	case "static":
+		name := fmt.Sprintf("%s.%d", cmd.File, cmd.Arg2)
+		if _, ok := p.Statics[name]; !ok {
+			if STATIC+len(p.Statics) >= STACK {
+				return fmt.Errorf("%s.vm:%d: too many static variables", cmd.File, cmd.Line)
+			}
+			p.Statics[name] = STATIC + len(p.Statics)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s.vm:%d: unknown segment %q", cmd.File, cmd.Line, cmd.Arg1)
+}
+
+// Frame 是调用栈中的一帧
+type Frame struct {
+	Function string
+	// Return 是返回后继续执行的命令下标
+	Return int
+}
+
+type VM struct {
+	RAM     [cpu.RAM_SIZE]uint16
+	PC      int
+	Steps   uint64
+	Program *Program
+	// Frames 是调用栈，第一帧是开始执行的函数
+	Frames []Frame
+
+	// writes 和 loops 用来检测死循环，和 cpu.CPU 一样
+	writes uint64
+	loops  map[int]loopState
+	halted bool
+}
+
+type loopState struct {
+	sp     uint16
+	writes uint64
+}
+
+// New 创建虚拟机。有 Sys.init 时从它开始执行，否则从第一条命令开始；RAM 全为 0，需要自己设置 SP 或调用 Bootstrap
+func New(prog *Program) *VM {
+	v := &VM{Program: prog, loops: make(map[int]loopState)}
+	v.Frames = []Frame{{Function: "", Return: len(prog.Commands)}}
+	if start, ok := prog.Functions["Sys.init"]; ok {
+		v.PC = start
+		v.Frames[0].Function = "Sys.init"
+	}
+	v.PC = v.skipLabels(v.PC)
+	return v
+}
+
+// Bootstrap 和翻译器生成的引导代码一样：SP=256，然后 call Sys.init 0。Sys.init 返回时程序结束
+func (v *VM) Bootstrap() error {
+	start, ok := v.Program.Functions["Sys.init"]
+	if !ok {
+		return fmt.Errorf("bootstrap needs a Sys.init function")
+	}
+	v.RAM[SP] = STACK
+	v.Frames = v.Frames[:0]
+	v.call("Sys.init", start, 0, len(v.Program.Commands))
+	v.Resume()
+	return nil
+}
+
+// Poke 从外部修改 RAM，之后的死循环检测重新开始
+func (v *VM) Poke(address int, value uint16) {
+	if v.RAM[address] != value {
+		v.writes += 1
+		v.halted = false
+	}
+	v.RAM[address] = value
+}
+
+// SetKey 设置当前按下的键，0 表示没有按键，同 cpu.CPU.SetKey
+func (v *VM) SetKey(key uint16) {
+	v.Poke(cpu.KBD, key)
+}
+
+// Resume 在从外部修改了 PC 等状态之后调用，重新开始死循环检测
+func (v *VM) Resume() {
+	v.loops = make(map[int]loopState)
+	v.halted = false
+}
+
+// Halted 判断程序是否已经结束：执行完了最后一条命令、从最外层函数返回，或者进入了不改变状态的死循环
+func (v *VM) Halted() bool {
+	return v.halted || v.PC >= len(v.Program.Commands)
+}
+
+// Current 返回下一条要执行的命令，程序结束时 ok 为 false
+func (v *VM) Current() (Command, bool) {
+	if v.PC >= len(v.Program.Commands) {
+		return Command{}, false
+	}
+	return v.Program.Commands[v.PC], true
+}
+
+// Function 返回当前所在的函数
+func (v *VM) Function() string {
+	return v.Frames[len(v.Frames)-1].Function
+}
+
+func (v *VM) write(address uint16, value uint16) {
+	address &= cpu.ADDRESS_MASK
+	if v.RAM[address] != value {
+		v.writes += 1
+		v.RAM[address] = value
+	}
+}
+
+func (v *VM) read(address uint16) uint16 {
+	return v.RAM[address&cpu.ADDRESS_MASK]
+}
+
+func (v *VM) push(value uint16) {
+	v.write(v.RAM[SP], value)
+	v.write(SP, v.RAM[SP]+1)
+}
+
+func (v *VM) pop() uint16 {
+	v.write(SP, v.RAM[SP]-1)
+	return v.read(v.RAM[SP])
+}
+
+// address 返回段中第 index 个元素的 RAM 地址
+func (v *VM) address(cmd Command) uint16 {
+	index := uint16(cmd.Arg2)
+	switch cmd.Arg1 {
+	case "local":
+		return v.RAM[LCL] + index
+	case "argument":
+		return v.RAM[ARG] + index
+	case "this":
+		return v.RAM[THIS] + index
+	case "that":
+		return v.RAM[THAT] + index
+	case "temp":
+		return TEMP + index
+	case "pointer":
+		return THIS + index
+	}
+	return uint16(v.Program.Statics[fmt.Sprintf("%s.%d", cmd.File, cmd.Arg2)])
+}
+
+// Step 执行一条命令，程序结束后什么也不做
+func (v *VM) Step() error {
+	cmd, ok := v.Current()
+	if !ok {
+		return nil
+	}
+	v.Steps += 1
+	next := v.PC + 1
+	switch cmd.Type {
+	case C_ARITHMETIC:
+		v.arithmetic(cmd.Arg1)
+	case C_PUSH:
+		if cmd.Arg1 == "constant" {
+			v.push(uint16(cmd.Arg2))
+		} else {
+			v.push(v.read(v.address(cmd)))
+		}
+	case C_POP:
+		address := v.address(cmd)
+		v.write(address, v.pop())
+	case C_GOTO:
+		next = v.jump(v.Program.targets[v.PC])
+	case C_IF:
+		if v.pop() != 0 {
+			next = v.jump(v.Program.targets[v.PC])
+		}
+	case C_FUNCTION:
+		for i := 0; i < cmd.Arg2; i++ {
+			v.push(0)
+		}
+	case C_CALL:
+		next = v.call(cmd.Arg1, v.Program.targets[v.PC], cmd.Arg2, next)
+	case C_RETURN:
+		next = v.ret()
+	}
+	v.PC = v.skipLabels(next)
+	return nil
+}
+
+// skipLabels 跳过 label 命令，label 不占执行步数，和官方 VM 模拟器一致
+func (v *VM) skipLabels(pc int) int {
+	for pc < len(v.Program.Commands) && v.Program.Commands[pc].Type == C_LABEL {
+		pc += 1
+	}
+	return pc
+}
+
+func (v *VM) arithmetic(op string) {
+	if op == "neg" || op == "not" {
+		x := v.pop()
+		if op == "neg" {
+			v.push(-x)
+		} else {
+			v.push(^x)
+		}
+		return
+	}
+	y := v.pop()
+	x := v.pop()
+	var res uint16
+	switch op {
+	case "add":
+		res = x + y
+	case "sub":
+		res = x - y
+	case "and":
+		res = x & y
+	case "or":
+		res = x | y
+	case "eq":
+		res = boolean(x == y)
+	case "gt":
+		res = boolean(int16(x) > int16(y))
+	case "lt":
+		res = boolean(int16(x) < int16(y))
+	}
+	v.push(res)
+}
+
+func boolean(b bool) uint16 {
+	if b {
+		return 0xffff
+	}
+	return 0
+}
+
+// jump 跳转到 target，回到上次跳到这里时完全相同的状态时认为程序进入了死循环
+func (v *VM) jump(target int) int {
+	state := loopState{sp: v.RAM[SP], writes: v.writes}
+	if prev, ok := v.loops[target]; ok && prev == state {
+		v.halted = true
+	}
+	v.loops[target] = state
+	return target
+}
+
+// call 保存返回地址、LCL、ARG、THIS、THAT，返回被调函数的下标。返回地址是命令的下标
+func (v *VM) call(function string, target int, nArgs int, ret int) int {
+	v.push(uint16(ret))
+	v.push(v.RAM[LCL])
+	v.push(v.RAM[ARG])
+	v.push(v.RAM[THIS])
+	v.push(v.RAM[THAT])
+	v.write(ARG, v.RAM[SP]-5-uint16(nArgs))
+	v.write(LCL, v.RAM[SP])
+	v.Frames = append(v.Frames, Frame{Function: function, Return: ret})
+	return target
+}
+
+// ret 恢复调用者的段并返回返回地址。最外层函数返回时程序结束
+func (v *VM) ret() int {
+	frame := v.RAM[LCL]
+	ret := int(v.read(frame - 5))
+	v.write(v.RAM[ARG], v.pop())
+	v.write(SP, v.RAM[ARG]+1)
+	v.write(THAT, v.read(frame-1))
+	v.write(THIS, v.read(frame-2))
+	v.write(ARG, v.read(frame-3))
+	v.write(LCL, v.read(frame-4))
+	if len(v.Frames) > 1 {
+		v.Frames = v.Frames[:len(v.Frames)-1]
+	}
+	if ret > len(v.Program.Commands) {
+		ret = len(v.Program.Commands)
+	}
+	return ret
+}
+
+// Run 执行最多 budget 条命令，程序结束时提前返回，结束的原因同 cpu.CPU.Run
+func (v *VM) Run(budget uint64) (string, error) {
+	for i := uint64(0); i < budget && !v.Halted(); i++ {
+		if err := v.Step(); err != nil {
+			return "", err
+		}
+	}
+	if v.Halted() {
+		return cpu.HALT_LOOP, nil
+	}
+	return cpu.HALT_BUDGET, nil
+}
@@ -0,0 +1,219 @@
+// Package asm 实现 Hack 汇编器：解析、两遍符号解析和编码，以及反汇编、listing 和符号文件等工具。
+// 源码通过 io.Reader 传入，所以既能处理文件，也能处理 VM 翻译器在内存中生成的汇编。
+package asm
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Options 控制一次汇编
+type Options struct {
+	// Name 是源文件名，用于报错信息和解析 .include 的相对路径
+	Name string
+	// Open 打开 .include 的文件，为 nil 时不支持 .include
+	Open func(name string) (io.ReadCloser, error)
+	// Optimize 在编码前做窥孔优化，省掉的指令数记在 Program.Saved 中
+	Optimize bool
+	// VarLimit 是变量区的上界（不含），变量分配到这里以及越过栈、屏幕和键盘时给出警告，为 0 时是 STACK_BASE
+	VarLimit int
+	// NoWarn 关闭其中的 lint 警告，名字见 Lints
+	NoWarn map[string]bool
+}
+
+// Source 是一个参与链接的源文件，每个源文件是一个模块
+type Source struct {
+	Name   string
+	Reader io.Reader
+}
+
+// Program 是汇编的结果
+type Program struct {
+	// Files 是所有源文件（包括 .include 的文件）的原始行，Instructions 是其中的指令（包括 label）
+	Files        []File
+	Instructions []Instruction
+	Symbols      *SymbolTable
+	// Saved 是优化省掉的指令数
+	Saved    int
+	varLimit int
+}
+
+// Words 返回编码后的机器码，下标即 ROM 地址
+func (p *Program) Words() []uint16 {
+	words := make([]uint16, 0, len(p.Instructions))
+	for _, inst := range p.Instructions {
+		if inst.Type == L_INSTRUCTION || inst.Code == "" {
+			continue
+		}
+		word, _ := strconv.ParseUint(inst.Code, 2, 16)
+		words = append(words, uint16(word))
+	}
+	return words
+}
+
+// WriteHack 以 .hack 格式输出，每行一个 16 位二进制数
+func (p *Program) WriteHack(w io.Writer) error {
+	return p.WriteFormat(w, FORMAT_HACK)
+}
+
+// Assemble 汇编 r 中的源码。有错误时 Program 仍会返回，但其中的编码不完整
+func Assemble(r io.Reader, opts Options) (*Program, Diagnostics) {
+	return AssembleAll([]Source{{Name: opts.Name, Reader: r}}, opts)
+}
+
+// AssembleAll 把多个源文件链接成一个 ROM 映像，按顺序排列。
+// label 默认只在定义它的文件内可见，用 .global 导出后其它文件才能引用；变量和预定义符号是全局的
+func AssembleAll(srcs []Source, opts Options) (*Program, Diagnostics) {
+	symTable := NewSymbolTable()
+	symTable.Init()
+	coder := NewCoder()
+	parser := NewParser(nil, opts.Name)
+
+	var diags Diagnostics
+	instructions := make([]Instruction, 0)
+	files := make([]File, 0, len(srcs))
+	globals := make(map[string]Global)
+	for _, src := range srcs {
+		p := NewParser(src.Reader, src.Name)
+		p.module = moduleName(src.Name)
+		p.open = opts.Open
+		instructions = append(instructions, p.Parse(&diags)...)
+		files = append(files, p.Files...)
+		for _, g := range p.Globals {
+			if prev, ok := globals[g.Name]; ok && prev.Module != g.Module {
+				diags.add(g.Pos, "duplicate global %s, already exported at %s", g.Name, prev.Pos)
+				continue
+			}
+			globals[g.Name] = g
+		}
+	}
+
+	lint := newLinter(opts.NoWarn, &diags)
+	for _, inst := range instructions {
+		if inst.Type == A_INSTRUCTION {
+			lint.reference(inst, parser)
+		}
+	}
+	saved := 0
+	if opts.Optimize {
+		var ok bool
+		instructions, saved, ok = Optimize(instructions)
+		if !ok {
+			diags.warn(Pos{File: opts.Name}, "optimization skipped: code addresses are computed from constants or label arithmetic")
+		}
+	}
+
+	// 只有一个模块时 label 不需要改名，多个模块时局部 label 在符号表中记为 模块/名字
+	scope := newScope(symTable, len(srcs) > 1, globals)
+	lineCnt := 0
+	for i := range instructions {
+		inst := &instructions[i]
+		inst.Address = lineCnt
+		if inst.Type == A_INSTRUCTION || inst.Type == C_INSTRUCTION {
+			lineCnt += 1
+		} else if inst.Type == L_INSTRUCTION && parser.validLabel(inst.Text) {
+			lint.defineLabel(scope, *inst, parser.Symbol(inst.Text))
+			scope.defineLabel(inst.Module, parser.Symbol(inst.Text), lineCnt, inst.Pos)
+		}
+	}
+	for _, g := range globals {
+		if !scope.defined(g.Module, g.Name) {
+			diags.add(g.Pos, "global %s is not defined in %s", g.Name, g.Module)
+		}
+	}
+	if lineCnt > ROM_SIZE {
+		for _, inst := range instructions {
+			if inst.Address == ROM_SIZE && inst.Type != L_INSTRUCTION {
+				diags.add(inst.Pos, "program needs %d words but ROM holds only %d", lineCnt, ROM_SIZE)
+				break
+			}
+		}
+	}
+
+	if opts.VarLimit == 0 {
+		opts.VarLimit = STACK_BASE
+	}
+	bounds := boundaries(opts.VarLimit)
+	crossed := 0
+	nextVar := 16
+	for i := range instructions {
+		inst := &instructions[i]
+		line := inst.Text
+		if inst.Type == A_INSTRUCTION {
+			var decimal int
+			sym := parser.Symbol(line)
+			if len(sym) == 0 {
+				// 已在 Parse 中报错
+				continue
+			}
+			if inst.expr != nil {
+				value, err := inst.expr.Eval(scope.lookup(inst.Module))
+				if err != nil {
+					diags.add(inst.at(1+err.(*ExprError).Offset), "%v", err)
+					continue
+				}
+				if value < 0 {
+					diags.add(inst.at(1), "negative value %d cannot be loaded by an A-instruction", value)
+					continue
+				}
+				if value > MAX_CONSTANT {
+					diags.add(inst.at(1), "value %d does not fit in 15 bits (max %d) and would be encoded as a C-instruction", value, MAX_CONSTANT)
+					continue
+				}
+				decimal = value
+			} else {
+				var ok bool
+				decimal, ok = scope.resolve(inst.Module, sym)
+				if !ok {
+					if owner, local := scope.owner(inst.Module, sym); local {
+						diags.add(inst.at(1), "%s is local to %s; export it with .global", sym, owner)
+						continue
+					}
+					if nextVar > MAX_CONSTANT {
+						diags.add(inst.at(1), "no RAM left for variable %s", sym)
+						continue
+					}
+					for crossed < len(bounds) && nextVar >= bounds[crossed].Address {
+						diags.warn(inst.at(1), "variable %s allocated at %d reaches %s (%d)", sym, nextVar, bounds[crossed].Name, bounds[crossed].Address)
+						crossed += 1
+					}
+					lint.newVariable(scope, *inst, sym)
+					decimal = nextVar
+					symTable.AddVariable(sym, nextVar, inst.Pos)
+					nextVar += 1
+				}
+			}
+			inst.Code = fmt.Sprintf("%016b", decimal)
+		} else if inst.Type == C_INSTRUCTION {
+			if i > 0 {
+				lint.useM(scope, *inst, instructions[i-1], parser)
+			}
+			f := LexC(line)
+			if f.HasDest && f.Dest.Text == "" {
+				diags.add(inst.at(f.Dest.Offset), "empty dest")
+			}
+			if f.HasJump && f.Jump.Text == "" {
+				diags.add(inst.at(f.Jump.Offset), "empty jump")
+			}
+			dest, ok := coder.Dest(parser.Dest(line))
+			if !ok {
+				diags.add(inst.Pos, "unknown dest %q", parser.Dest(line))
+			}
+			comp, ok := coder.Comp(parser.Comp(line))
+			if !ok {
+				diags.add(inst.at(parser.CompIndex(line)), "unknown comp %q", parser.Comp(line))
+			}
+			jump, ok := coder.Jump(parser.Jump(line))
+			if !ok {
+				diags.add(inst.at(parser.JumpIndex(line)), "unknown jump %q", parser.Jump(line))
+			}
+			inst.Code = "111" + comp + dest + jump
+		}
+	}
+
+	lint.finish(scope)
+
+	prog := &Program{Files: files, Instructions: instructions, Symbols: symTable, Saved: saved, varLimit: opts.VarLimit}
+	return prog, diags
+}
@@ -0,0 +1,125 @@
+package asm
+
+import (
+	"strings"
+)
+
+// destTable、compTable、jumpTable 是助记符到二进制的对照表，汇编和反汇编共用
+var destTable = map[string]string{
+	"":    "000",
+	"M":   "001",
+	"D":   "010",
+	"MD":  "011",
+	"A":   "100",
+	"AM":  "101",
+	"AD":  "110",
+	"AMD": "111",
+}
+
+var compTable = map[string]string{
+	"0":   "0101010",
+	"1":   "0111111",
+	"-1":  "0111010",
+	"D":   "0001100",
+	"A":   "0110000",
+	"M":   "1110000",
+	"!D":  "0001101",
+	"!A":  "0110001",
+	"!M":  "1110001",
+	"-D":  "0001111",
+	"-A":  "0110011",
+	"-M":  "1110011",
+	"D+1": "0011111",
+	"A+1": "0110111",
+	"M+1": "1110111",
+	"D-1": "0001110",
+	"A-1": "0110010",
+	"M-1": "1110010",
+	"D+A": "0000010",
+	"D+M": "1000010",
+	"D-A": "0010011",
+	"D-M": "1010011",
+	"A-D": "0000111",
+	"M-D": "1000111",
+	"D&A": "0000000",
+	"D&M": "1000000",
+	"D|A": "0010101",
+	"D|M": "1010101",
+}
+
+var jumpTable = map[string]string{
+	"":    "000",
+	"JGT": "001",
+	"JEQ": "010",
+	"JGE": "011",
+	"JLT": "100",
+	"JNE": "101",
+	"JLE": "110",
+	"JMP": "111",
+}
+
+type Coder struct {
+}
+
+func NewCoder() *Coder {
+	return &Coder{}
+}
+
+// Dest 接受寄存器的任意排列，如 DM、MA、DAM
+func (c *Coder) Dest(part string) (string, bool) {
+	return lookup(destTable, NormalizeDest(part), "000")
+}
+
+// Comp 接受加法、& 和 | 的交换写法，如 M+D、A+D、1+D
+func (c *Coder) Comp(part string) (string, bool) {
+	return lookup(compTable, NormalizeComp(part), "0000000")
+}
+
+func (c *Coder) Jump(part string) (string, bool) {
+	return lookup(jumpTable, part, "000")
+}
+
+// lookup 查表，找不到时返回 zero 和 false
+func lookup(table map[string]string, part string, zero string) (string, bool) {
+	bits, ok := table[part]
+	if !ok {
+		return zero, false
+	}
+	return bits, true
+}
+
+// NormalizeDest 把 dest 中的寄存器按 A、M、D 的顺序排列，如 DM -> MD、DAM -> AMD。
+// 有重复或未知的寄存器时原样返回
+func NormalizeDest(part string) string {
+	seen := make(map[rune]bool)
+	for _, r := range part {
+		if !strings.ContainsRune("AMD", r) || seen[r] {
+			return part
+		}
+		seen[r] = true
+	}
+	builder := strings.Builder{}
+	for _, r := range "AMD" {
+		if seen[r] {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// NormalizeComp 把交换律写法换成表中的写法，如 M+D -> D+M、1+D -> D+1、M&D -> D&M
+func NormalizeComp(part string) string {
+	if _, ok := compTable[part]; ok {
+		return part
+	}
+	for _, op := range []string{"+", "&", "|"} {
+		x, y, ok := strings.Cut(part, op)
+		if !ok || x == "" {
+			continue
+		}
+		if _, ok := compTable[y+op+x]; ok {
+			return y + op + x
+		}
+	}
+	return part
+}
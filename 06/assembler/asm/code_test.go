@@ -0,0 +1,68 @@
+package asm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNormalizeComp(t *testing.T) {
+	tests := []struct{ part, want string }{
+		{"D+M", "D+M"},
+		{"M+D", "D+M"},
+		{"A+D", "D+A"},
+		{"1+D", "D+1"},
+		{"1+M", "M+1"},
+		{"A&D", "D&A"},
+		{"M&D", "D&M"},
+		{"M|D", "D|M"},
+		// 减法不满足交换律
+		{"D-M", "D-M"},
+		{"M-D", "M-D"},
+		{"-1+D", "-1+D"},
+		{"+D", "+D"},
+		{"D+D", "D+D"},
+	}
+	for _, test := range tests {
+		if got := NormalizeComp(test.part); got != test.want {
+			t.Errorf("NormalizeComp(%q) = %q, want %q", test.part, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeDest(t *testing.T) {
+	tests := []struct{ part, want string }{
+		{"", ""},
+		{"DM", "MD"},
+		{"MA", "AM"},
+		{"DA", "AD"},
+		{"DAM", "AMD"},
+		{"MDA", "AMD"},
+		{"DD", "DD"},
+		{"X", "X"},
+	}
+	for _, test := range tests {
+		if got := NormalizeDest(test.part); got != test.want {
+			t.Errorf("NormalizeDest(%q) = %q, want %q", test.part, got, test.want)
+		}
+	}
+}
+
+// TestEncodeCommuted 交换写法和表中写法编码相同，不能被编码成 0
+func TestEncodeCommuted(t *testing.T) {
+	tests := []struct {
+		line string
+		want uint16
+	}{
+		{"M=M+D", 0xf088},
+		{"M=D+M", 0xf088},
+		{"D=A+D", 0xe090},
+		{"DM=1+M", 0xfdd8},
+		{"D=A&D", 0xe010},
+		{"A=M|D", 0xf560},
+		{"0;JMP", 0xea87},
+	}
+	for _, test := range tests {
+		prog := assemble(t, test.line, test.line+"\n", Options{})
+		sameWords(t, fmt.Sprintf("%q", test.line), prog.Words(), []uint16{test.want})
+	}
+}
@@ -0,0 +1,78 @@
+package asm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Pos 是源文件中的位置，行和列都从 1 开始
+type Pos struct {
+	File string
+	Line int
+	Col  int
+}
+
+func (p Pos) String() string {
+	if p.Line == 0 {
+		return p.File
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
+// Diagnostic 是一条带位置的错误或警告，警告不影响汇编结果
+type Diagnostic struct {
+	Pos     Pos
+	Msg     string
+	Warning bool
+}
+
+func (d Diagnostic) Error() string {
+	if d.Warning {
+		return fmt.Sprintf("%s: warning: %s", d.Pos, d.Msg)
+	}
+	return fmt.Sprintf("%s: %s", d.Pos, d.Msg)
+}
+
+// Diagnostics 收集所有错误，汇编结束后一起输出，而不是遇到第一个错误就退出
+type Diagnostics []Diagnostic
+
+func (ds *Diagnostics) add(pos Pos, format string, args ...any) {
+	*ds = append(*ds, Diagnostic{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (ds *Diagnostics) warn(pos Pos, format string, args ...any) {
+	*ds = append(*ds, Diagnostic{Pos: pos, Msg: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// HasErrors 判断是否有警告以外的错误
+func (ds Diagnostics) HasErrors() bool {
+	for _, d := range ds {
+		if !d.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// Print 按出现位置排序后输出，不同文件按第一次报错的先后排列
+func (ds Diagnostics) Print(w io.Writer) {
+	order := make(map[string]int)
+	for _, d := range ds {
+		if _, ok := order[d.Pos.File]; !ok {
+			order[d.Pos.File] = len(order)
+		}
+	}
+	sort.SliceStable(ds, func(i, j int) bool {
+		if ds[i].Pos.File != ds[j].Pos.File {
+			return order[ds[i].Pos.File] < order[ds[j].Pos.File]
+		}
+		if ds[i].Pos.Line != ds[j].Pos.Line {
+			return ds[i].Pos.Line < ds[j].Pos.Line
+		}
+		return ds[i].Pos.Col < ds[j].Pos.Col
+	})
+	for _, d := range ds {
+		fmt.Fprintln(w, d.Error())
+	}
+}
@@ -0,0 +1,197 @@
+package asm
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Word 是反汇编得到的一条指令
+type Word struct {
+	Value uint16
+	Type  string
+	// C 指令的三个字段
+	Dest string
+	Comp string
+	Jump string
+}
+
+// String 返回指令的汇编形式，A 指令是数字地址
+func (w Word) String() string {
+	if w.Type == A_INSTRUCTION {
+		return "@" + strconv.Itoa(int(w.Value))
+	}
+	text := w.Comp
+	if w.Dest != "" {
+		text = w.Dest + "=" + text
+	}
+	if w.Jump != "" {
+		text += ";" + w.Jump
+	}
+	return text
+}
+
+// Disassembler 把 .hack 中的二进制指令还原成汇编，和 Coder 共用同一套对照表
+type Disassembler struct {
+	destNames map[string]string
+	compNames map[string]string
+	jumpNames map[string]string
+}
+
+func NewDisassembler() *Disassembler {
+	return &Disassembler{
+		destNames: invert(destTable),
+		compNames: invert(compTable),
+		jumpNames: invert(jumpTable),
+	}
+}
+
+func invert(table map[string]string) map[string]string {
+	res := make(map[string]string, len(table))
+	for name, bits := range table {
+		res[bits] = name
+	}
+	return res
+}
+
+// ReadHack 读取 .hack 格式的程序，每行一个 16 位二进制数
+func ReadHack(r io.Reader) ([]uint16, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	words := make([]uint16, 0)
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if len(line) != 16 {
+			return nil, fmt.Errorf("line %d: expected 16 binary digits, got %q", i+1, line)
+		}
+		value, err := strconv.ParseUint(line, 2, 16)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid binary word %q", i+1, line)
+		}
+		words = append(words, uint16(value))
+	}
+	return words, nil
+}
+
+// Decode 解码一个字，无法用汇编精确表示的字返回错误
+func (d *Disassembler) Decode(value uint16) (Word, error) {
+	if value&0x8000 == 0 {
+		return Word{Value: value, Type: A_INSTRUCTION}, nil
+	}
+	bits := fmt.Sprintf("%016b", value)
+	if bits[:3] != "111" {
+		return Word{}, fmt.Errorf("word %s has unused bits cleared and cannot be re-assembled", bits)
+	}
+	comp, ok := d.compNames[bits[3:10]]
+	if !ok {
+		return Word{}, fmt.Errorf("word %s has unknown comp bits %s", bits, bits[3:10])
+	}
+	return Word{
+		Value: value,
+		Type:  C_INSTRUCTION,
+		Dest:  d.destNames[bits[10:13]],
+		Comp:  comp,
+		Jump:  d.jumpNames[bits[13:]],
+	}, nil
+}
+
+// Disassemble 返回汇编源码。跳转目标会生成 label，symbols 非空时用其中的名字替换地址
+func (d *Disassembler) Disassemble(values []uint16, symbols []Symbol) (string, error) {
+	words := make([]Word, len(values))
+	for i, value := range values {
+		word, err := d.Decode(value)
+		if err != nil {
+			return "", fmt.Errorf("address %d: %v", i, err)
+		}
+		words[i] = word
+	}
+
+	labelNames := make(map[int]string)
+	dataNames := make(map[int][]Symbol)
+	// taken 是 symbols 中的所有名字，生成的 label 不能和它们重名，否则重新汇编的结果会不同
+	taken := make(map[string]bool)
+	for _, sym := range symbols {
+		if !IsSymbol(sym.Name) {
+			continue
+		}
+		taken[sym.Name] = true
+		if sym.Kind == SYM_LABEL {
+			if _, ok := labelNames[sym.Address]; !ok {
+				labelNames[sym.Address] = sym.Name
+			}
+		} else {
+			dataNames[sym.Address] = append(dataNames[sym.Address], sym)
+		}
+	}
+
+	// @addr 后面紧跟带跳转的 C 指令时，addr 是跳转目标
+	labels := make(map[int]string)
+	isTarget := make(map[int]bool)
+	for i := 1; i < len(words); i++ {
+		prev := words[i-1]
+		if words[i].Type != C_INSTRUCTION || words[i].Jump == "" || prev.Type != A_INSTRUCTION {
+			continue
+		}
+		target := int(prev.Value)
+		if target > len(words) {
+			continue
+		}
+		isTarget[i-1] = true
+		if _, ok := labels[target]; ok {
+			continue
+		}
+		if name, ok := labelNames[target]; ok {
+			labels[target] = name
+		} else {
+			name := fmt.Sprintf("L%d", target)
+			for n := 1; taken[name]; n++ {
+				name = fmt.Sprintf("L%d_%d", target, n)
+			}
+			taken[name] = true
+			labels[target] = name
+		}
+	}
+
+	// 变量按第一次出现的顺序从 16 开始分配，只有顺序一致时才能用名字替换，否则保留数字
+	assigned := make(map[string]bool)
+	nextVar := 16
+	builder := strings.Builder{}
+	for i, word := range words {
+		if name, ok := labels[i]; ok {
+			builder.WriteString(fmt.Sprintf("(%s)\n", name))
+		}
+		if word.Type == C_INSTRUCTION {
+			builder.WriteString("    " + word.String() + "\n")
+			continue
+		}
+
+		operand := strconv.Itoa(int(word.Value))
+		if isTarget[i] {
+			operand = labels[int(word.Value)]
+		} else {
+			for _, sym := range dataNames[int(word.Value)] {
+				if sym.Kind == SYM_PREDEFINED || assigned[sym.Name] {
+					operand = sym.Name
+					break
+				}
+				if sym.Address == nextVar {
+					assigned[sym.Name] = true
+					nextVar += 1
+					operand = sym.Name
+					break
+				}
+			}
+		}
+		builder.WriteString(fmt.Sprintf("    @%s\n", operand))
+	}
+	if name, ok := labels[len(words)]; ok {
+		builder.WriteString(fmt.Sprintf("(%s)\n", name))
+	}
+	return builder.String(), nil
+}
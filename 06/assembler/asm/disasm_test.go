@@ -0,0 +1,116 @@
+package asm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ROOT 是仓库的根目录，go test 在包所在的目录中执行
+const ROOT = "../../.."
+
+// coursePrograms 返回 04 和 06 中课程提供的 .asm 程序
+func coursePrograms(t *testing.T) []string {
+	files := make([]string, 0)
+	for _, pattern := range []string{"04/*/*.asm", "06/*/*.asm"} {
+		matches, err := filepath.Glob(filepath.Join(ROOT, pattern))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		t.Fatal("no course programs found")
+	}
+	return files
+}
+
+// assemble 汇编 src，有错误时测试失败
+func assemble(t *testing.T, name string, src string, opts Options) *Program {
+	t.Helper()
+	opts.Name = name
+	prog, diags := Assemble(strings.NewReader(src), opts)
+	if diags.HasErrors() {
+		t.Fatalf("%s: %v", name, diags)
+	}
+	return prog
+}
+
+func sameWords(t *testing.T, name string, got []uint16, want []uint16) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d words, want %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s: word %d is %016b, want %016b", name, i, got[i], want[i])
+		}
+	}
+}
+
+// TestDisassembleRoundTrip 反汇编课程程序再汇编，结果和原来的机器码相同，有没有符号表都一样
+func TestDisassembleRoundTrip(t *testing.T) {
+	for _, file := range coursePrograms(t) {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prog := assemble(t, file, string(src), Options{})
+		words := prog.Words()
+		for _, symbols := range [][]Symbol{nil, prog.Symbols.Symbols()} {
+			text, err := NewDisassembler().Disassemble(words, symbols)
+			if err != nil {
+				t.Fatalf("%s: %v", file, err)
+			}
+			again := assemble(t, file+" (disassembled)", text, Options{})
+			sameWords(t, file, again.Words(), words)
+		}
+	}
+}
+
+// TestDisassembleGeneratedLabels 生成的跳转 label 不能和符号表中的名字相同
+func TestDisassembleGeneratedLabels(t *testing.T) {
+	src := "@0\nD=M\n(L12)\n@L12\nD;JGT\n" + strings.Repeat("D=D-1\n", 7) + "@12\n0;JMP\n(END)\n@END\n0;JMP\n"
+	prog := assemble(t, "labels.asm", src, Options{})
+	text, err := NewDisassembler().Disassemble(prog.Words(), prog.Symbols.Symbols())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(text, "(L12)") != 1 {
+		t.Fatalf("expected a single (L12) label in\n%s", text)
+	}
+	again := assemble(t, "labels.dis.asm", text, Options{})
+	sameWords(t, "labels.asm", again.Words(), prog.Words())
+}
+
+// TestDecode 检查几种指令的反汇编文本
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		value uint16
+		want  string
+	}{
+		{0x0005, "@5"},
+		{0x7fff, "@32767"},
+		{0xfc10, "D=M"},
+		{0xec10, "D=A"},
+		{0xe308, "M=D"},
+		{0xfca8, "AM=M-1"},
+		{0xea87, "0;JMP"},
+		{0xe302, "D;JEQ"},
+		{0xf088, "M=D+M"},
+	}
+	d := NewDisassembler()
+	for _, test := range tests {
+		word, err := d.Decode(test.value)
+		if err != nil {
+			t.Fatalf("Decode(%#04x): %v", test.value, err)
+		}
+		if got := word.String(); got != test.want {
+			t.Errorf("Decode(%#04x) = %q, want %q", test.value, got, test.want)
+		}
+	}
+	if _, err := d.Decode(0xe000 | 0b101011<<6); err == nil {
+		t.Errorf("expected an error for unknown comp bits")
+	}
+}
@@ -0,0 +1,270 @@
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ExprError 是常量表达式中的错误，Offset 是出错处在表达式中的偏移
+type ExprError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *ExprError) Error() string {
+	return e.Msg
+}
+
+// Expr 是 A 指令中的常量表达式，如 @SCREEN+32、@LOOP-1、@(0x4000|'A')*2
+type Expr interface {
+	// Eval 求值，lookup 用来查符号的地址
+	Eval(lookup func(name string) (int, bool)) (int, error)
+}
+
+type numExpr struct {
+	value int
+}
+
+type symExpr struct {
+	name   string
+	offset int
+}
+
+type unaryExpr struct {
+	op byte
+	x  Expr
+}
+
+type binaryExpr struct {
+	op     byte
+	x, y   Expr
+	offset int
+}
+
+func (e numExpr) Eval(lookup func(string) (int, bool)) (int, error) {
+	return e.value, nil
+}
+
+func (e symExpr) Eval(lookup func(string) (int, bool)) (int, error) {
+	value, ok := lookup(e.name)
+	if !ok {
+		return 0, &ExprError{Offset: e.offset, Msg: fmt.Sprintf("undefined symbol %q in expression", e.name)}
+	}
+	return value, nil
+}
+
+func (e unaryExpr) Eval(lookup func(string) (int, bool)) (int, error) {
+	x, err := e.x.Eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	if e.op == '~' {
+		return ^x, nil
+	}
+	return -x, nil
+}
+
+func (e binaryExpr) Eval(lookup func(string) (int, bool)) (int, error) {
+	x, err := e.x.Eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	y, err := e.y.Eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	switch e.op {
+	case '+':
+		return x + y, nil
+	case '-':
+		return x - y, nil
+	case '*':
+		return x * y, nil
+	case '/':
+		if y == 0 {
+			return 0, &ExprError{Offset: e.offset, Msg: "division by zero"}
+		}
+		return x / y, nil
+	case '&':
+		return x & y, nil
+	case '|':
+		return x | y, nil
+	}
+	return 0, &ExprError{Offset: e.offset, Msg: fmt.Sprintf("unknown operator %q", e.op)}
+}
+
+// exprSymbols 返回表达式中引用的符号
+func exprSymbols(e Expr) []string {
+	switch e := e.(type) {
+	case symExpr:
+		return []string{e.name}
+	case unaryExpr:
+		return exprSymbols(e.x)
+	case binaryExpr:
+		return append(exprSymbols(e.x), exprSymbols(e.y)...)
+	}
+	return nil
+}
+
+// ParseExpr 解析常量表达式。支持十进制、0x 十六进制、0b 二进制、'A' 字符常量和符号，
+// 运算符按优先级从低到高为 |、&、+ -、* /、一元 - ~，可以用括号
+func ParseExpr(src string) (Expr, error) {
+	p := &exprParser{src: src}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos])
+	}
+	return e, nil
+}
+
+type exprParser struct {
+	src string
+	pos int
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return &ExprError{Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// accept 跳过空白，下一个字符在 ops 中时消费它
+func (p *exprParser) accept(ops string) (byte, bool) {
+	p.skipSpace()
+	if p.pos < len(p.src) && strings.IndexByte(ops, p.src[p.pos]) >= 0 {
+		p.pos++
+		return p.src[p.pos-1], true
+	}
+	return 0, false
+}
+
+// parseBinary 解析一层左结合的二元运算
+func (p *exprParser) parseBinary(ops string, next func() (Expr, error)) (Expr, error) {
+	x, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(ops)
+		if !ok {
+			return x, nil
+		}
+		offset := p.pos - 1
+		y, err := next()
+		if err != nil {
+			return nil, err
+		}
+		x = binaryExpr{op: op, x: x, y: y, offset: offset}
+	}
+}
+
+func (p *exprParser) parseOr() (Expr, error) {
+	return p.parseBinary("|", p.parseAnd)
+}
+
+func (p *exprParser) parseAnd() (Expr, error) {
+	return p.parseBinary("&", p.parseSum)
+}
+
+func (p *exprParser) parseSum() (Expr, error) {
+	return p.parseBinary("+-", p.parseProduct)
+}
+
+func (p *exprParser) parseProduct() (Expr, error) {
+	return p.parseBinary("*/", p.parseUnary)
+}
+
+func (p *exprParser) parseUnary() (Expr, error) {
+	if op, ok := p.accept("-~"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryExpr{op: op, x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (Expr, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of expression")
+	}
+	start := p.pos
+	c := p.src[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, p.errorf("missing ')'")
+		}
+		return e, nil
+	case c == '\'':
+		return p.parseChar()
+	case unicode.IsDigit(rune(c)):
+		for p.pos < len(p.src) && isSymbolChar(p.src[p.pos]) {
+			p.pos++
+		}
+		text := p.src[start:p.pos]
+		value, err := parseNumber(text)
+		if err != nil {
+			return nil, &ExprError{Offset: start, Msg: fmt.Sprintf("invalid constant %q", text)}
+		}
+		return numExpr{value: value}, nil
+	case isSymbolChar(c):
+		for p.pos < len(p.src) && isSymbolChar(p.src[p.pos]) {
+			p.pos++
+		}
+		return symExpr{name: p.src[start:p.pos], offset: start}, nil
+	}
+	return nil, p.errorf("unexpected %q", c)
+}
+
+// parseChar 解析 'A' 形式的字符常量，支持 \' 和 \\ 转义
+func (p *exprParser) parseChar() (Expr, error) {
+	start := p.pos
+	p.pos++
+	if p.pos < len(p.src) && p.src[p.pos] == '\\' {
+		p.pos++
+	}
+	if p.pos+1 >= len(p.src) || p.src[p.pos+1] != '\'' {
+		return nil, &ExprError{Offset: start, Msg: "malformed character constant"}
+	}
+	value := int(p.src[p.pos])
+	p.pos += 2
+	return numExpr{value: value}, nil
+}
+
+// parseNumber 解析十进制、0x 十六进制和 0b 二进制数
+func parseNumber(text string) (int, error) {
+	lower := strings.ToLower(text)
+	var value int64
+	var err error
+	switch {
+	case strings.HasPrefix(lower, "0x"):
+		value, err = strconv.ParseInt(lower[2:], 16, 32)
+	case strings.HasPrefix(lower, "0b"):
+		value, err = strconv.ParseInt(lower[2:], 2, 32)
+	default:
+		value, err = strconv.ParseInt(text, 10, 32)
+	}
+	return int(value), err
+}
+
+func isSymbolChar(c byte) bool {
+	return c == '_' || c == '.' || c == '$' || c == ':' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
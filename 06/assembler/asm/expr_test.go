@@ -0,0 +1,119 @@
+package asm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseExpr(t *testing.T) {
+	symbols := map[string]int{"SCREEN": 16384, "LOOP": 10, "x.y$z": 3}
+	lookup := func(name string) (int, bool) {
+		value, ok := symbols[name]
+		return value, ok
+	}
+	tests := []struct {
+		src  string
+		want int
+	}{
+		{"42", 42},
+		{"0x4000", 16384},
+		{"0b101", 5},
+		{"'A'", 65},
+		{"'\\''", 39},
+		{"SCREEN+32", 16416},
+		{"LOOP-1", 9},
+		{"x.y$z*2", 6},
+		// * / 比 + - 优先，+ - 比 & 优先，& 比 | 优先
+		{"1+2*3", 7},
+		{"(1+2)*3", 9},
+		{"10-4-3", 3},
+		{"100/10/5", 2},
+		{"1|2&3", 3},
+		{"6&3+1", 4},
+		{"-2*-3", 6},
+		{"~0&0x7fff", 0x7fff},
+		{" ( SCREEN | 'A' ) * 2 ", (16384 | 65) * 2},
+	}
+	for _, test := range tests {
+		e, err := ParseExpr(test.src)
+		if err != nil {
+			t.Errorf("ParseExpr(%q): %v", test.src, err)
+			continue
+		}
+		got, err := e.Eval(lookup)
+		if err != nil || got != test.want {
+			t.Errorf("ParseExpr(%q) = %d, %v, want %d", test.src, got, err, test.want)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []struct {
+		src    string
+		offset int
+		msg    string
+	}{
+		{"1+", 2, "unexpected end of expression"},
+		{"(1+2", 4, "missing ')'"},
+		{"1 # 2", 2, "unexpected '#'"},
+		{"2 3", 2, "unexpected '3'"},
+		{"1+0xZZ", 2, "invalid constant \"0xZZ\""},
+		{"0x100000000", 0, "invalid constant"},
+		{"'AB'", 0, "malformed character constant"},
+	}
+	for _, test := range tests {
+		_, err := ParseExpr(test.src)
+		exprErr, ok := err.(*ExprError)
+		if !ok {
+			t.Errorf("ParseExpr(%q) = %v, want an *ExprError", test.src, err)
+			continue
+		}
+		if exprErr.Offset != test.offset || !strings.Contains(exprErr.Msg, test.msg) {
+			t.Errorf("ParseExpr(%q) error at %d %q, want at %d %q", test.src, exprErr.Offset, exprErr.Msg, test.offset, test.msg)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	lookup := func(name string) (int, bool) { return 0, false }
+	for _, test := range []struct{ src, msg string }{
+		{"1/(2-2)", "division by zero"},
+		{"1+NOPE", "NOPE"},
+	} {
+		e, err := ParseExpr(test.src)
+		if err != nil {
+			t.Fatalf("ParseExpr(%q): %v", test.src, err)
+		}
+		if _, err := e.Eval(lookup); err == nil || !strings.Contains(err.Error(), test.msg) {
+			t.Errorf("Eval(%q) = %v, want an error with %q", test.src, err, test.msg)
+		}
+	}
+}
+
+// TestExprOverflow 检查 A 指令装不下的值报错的位置，而不是被编码成 C 指令
+func TestExprOverflow(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"@32767\n", ""},
+		{"@0x7fff\n", ""},
+		{"@32768\n", "1:2: value 32768 does not fit in 15 bits"},
+		{"@16384*2\n", "1:2: value 32768 does not fit in 15 bits"},
+		{"@SCREEN+0x4000\n", "1:2: value 32768 does not fit in 15 bits"},
+		{"@0-1\n", "1:2: negative value -1"},
+		{"D=A\n@1+\n", "2:4: unexpected end of expression"},
+	}
+	for _, test := range tests {
+		prog, diags := Assemble(strings.NewReader(test.src), Options{Name: "t.asm"})
+		if test.want == "" {
+			if diags.HasErrors() || len(prog.Words()) != 1 || prog.Words()[0] != 0x7fff {
+				t.Errorf("%q: %v, words %v", test.src, diags, prog.Words())
+			}
+			continue
+		}
+		if !diags.HasErrors() || !strings.Contains(diags[0].Error(), "t.asm:"+test.want) {
+			t.Errorf("%q: got %v, want %q", test.src, diags, test.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package asm
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// INDENT 是格式化后指令的缩进，label 和伪指令顶格
+const INDENT = "    "
+
+// fmtLine 是格式化中的一行，code 是不含缩进和注释的代码，comment 是行尾或整行注释
+type fmtLine struct {
+	indent  string
+	code    string
+	comment string
+}
+
+// Format 把源码改写为规范的格式：label 和伪指令顶格，指令缩进，连续几行的行尾注释对齐，
+// 助记符规范化（如 DM=M+D -> MD=D+M），多个空行合并为一个。
+// 有语法错误时不做格式化，返回的 Diagnostics 中有错误。行尾是 \r\n 的文件仍输出 \r\n
+func Format(src []byte, name string) ([]byte, Diagnostics) {
+	var diags Diagnostics
+	p := NewParser(bytes.NewReader(src), name)
+	p.open = func(string) (io.ReadCloser, error) {
+		// 只格式化这一个文件，不读 .include 的文件
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	p.Parse(&diags)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	lines := make([]fmtLine, 0, len(p.Lines))
+	defining := false
+	for _, raw := range p.Lines {
+		code := strings.TrimSpace(stripComment(raw))
+		comment := strings.TrimSpace(raw[len(stripComment(raw)):])
+		if code == "" && comment == "" {
+			// 去掉开头和连续的空行
+			if len(lines) > 0 && lines[len(lines)-1] != (fmtLine{}) {
+				lines = append(lines, fmtLine{})
+			}
+			continue
+		}
+		line := fmtLine{code: code, comment: comment}
+		switch {
+		case code == "":
+		case strings.HasPrefix(code, ".macro"):
+			line.code = p.formatDirective(code)
+			defining = true
+		case code == ".endm":
+			defining = false
+		default:
+			line.code, line.indent = p.formatCode(code, defining)
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == (fmtLine{}) {
+		lines = lines[:len(lines)-1]
+	}
+	indentComments(lines)
+
+	eol := "\n"
+	if bytes.Contains(src, []byte("\r\n")) {
+		eol = "\r\n"
+	}
+	return writeLines(lines, eol), diags
+}
+
+// formatCode 格式化一行代码，返回代码和缩进。宏定义中的行可能含有 \param，只规范化空白
+func (p *Parser) formatCode(code string, defining bool) (string, string) {
+	if m, args, ok := p.invocation(code); ok && len(args) > 0 {
+		return m.Name + " " + strings.Join(args, ", "), INDENT
+	} else if ok {
+		return m.Name, INDENT
+	}
+	if strings.HasPrefix(code, ".") {
+		return p.formatDirective(code), ""
+	}
+	switch p.InstructionType(code) {
+	case A_INSTRUCTION:
+		return "@" + p.Symbol(code), INDENT
+	case L_INSTRUCTION:
+		return "(" + p.Symbol(code) + ")", ""
+	}
+	f := LexC(code)
+	dest, comp, jump := f.Dest.Text, f.Comp.Text, f.Jump.Text
+	if !defining {
+		dest, comp = NormalizeDest(dest), NormalizeComp(comp)
+	}
+	// 保留空 dest 或 jump 的分隔符，让汇编器报告错误，而不是把 =M 格式化成 M
+	if dest != "" || f.HasDest {
+		comp = dest + "=" + comp
+	}
+	if jump != "" || f.HasJump {
+		comp = comp + ";" + jump
+	}
+	return comp, INDENT
+}
+
+// formatDirective 规范化伪指令中的空白，如 ".macro  LOADI reg,value" -> ".macro LOADI reg, value"
+func (p *Parser) formatDirective(code string) string {
+	fields := strings.Fields(code)
+	arg := strings.TrimSpace(code[len(fields[0]):])
+	switch fields[0] {
+	case ".macro":
+		if len(fields) == 1 {
+			return code
+		}
+		params := splitArgs(strings.Join(fields[2:], " "))
+		if len(params) == 0 {
+			return ".macro " + fields[1]
+		}
+		return ".macro " + fields[1] + " " + strings.Join(params, ", ")
+	case ".global":
+		return ".global " + strings.Join(splitArgs(arg), ", ")
+	}
+	return fields[0] + " " + arg
+}
+
+// indentComments 决定整行注释的缩进：紧挨在代码上方的注释和这行代码缩进相同，其它注释顶格
+func indentComments(lines []fmtLine) {
+	indent := ""
+	for i := len(lines) - 1; i >= 0; i-- {
+		switch {
+		case lines[i] == fmtLine{}:
+			indent = ""
+		case lines[i].code != "":
+			indent = lines[i].indent
+		default:
+			lines[i].indent = indent
+		}
+	}
+}
+
+// writeLines 输出各行。空行或整行注释分隔的一组代码中，行尾注释对齐到同一列
+func writeLines(lines []fmtLine, eol string) []byte {
+	buf := bytes.Buffer{}
+	for start := 0; start < len(lines); {
+		if lines[start].code == "" {
+			writeLine(&buf, lines[start].indent+lines[start].comment, eol)
+			start += 1
+			continue
+		}
+		end, width := start, 0
+		for ; end < len(lines) && lines[end].code != ""; end++ {
+			if n := len(lines[end].indent + lines[end].code); lines[end].comment != "" && n > width {
+				width = n
+			}
+		}
+		for _, line := range lines[start:end] {
+			text := line.indent + line.code
+			if line.comment != "" {
+				text += strings.Repeat(" ", width-len(text)+1) + line.comment
+			}
+			writeLine(&buf, text, eol)
+		}
+		start = end
+	}
+	return buf.Bytes()
+}
+
+func writeLine(buf *bytes.Buffer, text string, eol string) {
+	buf.WriteString(strings.TrimRight(text, " \t"))
+	buf.WriteString(eol)
+}
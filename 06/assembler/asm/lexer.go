@@ -0,0 +1,82 @@
+package asm
+
+import (
+	"strings"
+)
+
+// stripComment 去掉行尾的 // 注释，字符常量（如 @'/'）中的 / 不算注释
+func stripComment(line string) string {
+	inChar := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\'' && !inChar:
+			inChar = true
+		case line[i] == '\'' && inChar && line[i-1] != '\\':
+			inChar = false
+		case line[i] == '/' && !inChar && i+1 < len(line) && line[i+1] == '/':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// Field 是 C 指令中的一个字段，Text 中的空白已去掉，Offset 是字段在指令中的偏移
+type Field struct {
+	Text   string
+	Offset int
+}
+
+// CFields 是 C 指令 dest=comp;jump 的三个字段，没有 dest 或 jump 时对应的 Text 为空。
+// HasDest 和 HasJump 表示是否有 = 和 ;，用来区分没有字段和有分隔符但字段为空（如 =M、D;）
+type CFields struct {
+	Dest    Field
+	Comp    Field
+	Jump    Field
+	HasDest bool
+	HasJump bool
+}
+
+// LexC 把 C 指令切分为 dest、comp、jump，字段内和字段间可以有任意空白，如 D = M + 1 ; JGT
+func LexC(line string) CFields {
+	equalIdx := strings.IndexByte(line, '=')
+	semiIdx := strings.IndexByte(line, ';')
+	if equalIdx >= 0 && semiIdx >= 0 && equalIdx > semiIdx {
+		// = 出现在 ; 之后，当作 jump 的一部分
+		equalIdx = -1
+	}
+
+	compStart, compEnd := 0, len(line)
+	fields := CFields{}
+	if equalIdx >= 0 {
+		fields.Dest = field(line, 0, equalIdx)
+		fields.HasDest = true
+		compStart = equalIdx + 1
+	} else {
+		fields.Dest = Field{Offset: 0}
+	}
+	if semiIdx >= 0 {
+		fields.Jump = field(line, semiIdx+1, len(line))
+		fields.HasJump = true
+		compEnd = semiIdx
+	} else {
+		fields.Jump = Field{Offset: len(line)}
+	}
+	fields.Comp = field(line, compStart, compEnd)
+	return fields
+}
+
+// field 返回 line[start:end] 去掉空白后的字段，偏移指向其中第一个非空白字符
+func field(line string, start int, end int) Field {
+	part := line[start:end]
+	offset := start
+	if trimmed := strings.TrimLeft(part, " \t"); len(trimmed) > 0 {
+		offset += len(part) - len(trimmed)
+	}
+	text := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, part)
+	return Field{Text: text, Offset: offset}
+}
@@ -0,0 +1,25 @@
+package asm
+
+import "testing"
+
+func TestLexC(t *testing.T) {
+	tests := []struct {
+		line string
+		want CFields
+	}{
+		{"D=M", CFields{Dest: Field{"D", 0}, Comp: Field{"M", 2}, Jump: Field{"", 3}, HasDest: true}},
+		{"0;JMP", CFields{Dest: Field{"", 0}, Comp: Field{"0", 0}, Jump: Field{"JMP", 2}, HasJump: true}},
+		{"AM=M-1;JNE", CFields{Dest: Field{"AM", 0}, Comp: Field{"M-1", 3}, Jump: Field{"JNE", 7}, HasDest: true, HasJump: true}},
+		{"D = M + 1 ; JGT", CFields{Dest: Field{"D", 0}, Comp: Field{"M+1", 4}, Jump: Field{"JGT", 12}, HasDest: true, HasJump: true}},
+		{"M+D", CFields{Dest: Field{"", 0}, Comp: Field{"M+D", 0}, Jump: Field{"", 3}}},
+		{"=M", CFields{Dest: Field{"", 0}, Comp: Field{"M", 1}, Jump: Field{"", 2}, HasDest: true}},
+		{"D;", CFields{Dest: Field{"", 0}, Comp: Field{"D", 0}, Jump: Field{"", 2}, HasJump: true}},
+		// ; 之后的 = 属于 jump
+		{"D;J=1", CFields{Dest: Field{"", 0}, Comp: Field{"D", 0}, Jump: Field{"J=1", 2}, HasJump: true}},
+	}
+	for _, test := range tests {
+		if got := LexC(test.line); got != test.want {
+			t.Errorf("LexC(%q) = %+v, want %+v", test.line, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package asm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	ROM_SIZE = 32768
+	// STACK_BASE 是栈的起始地址，也是默认的变量区上界
+	STACK_BASE = 256
+	SCREEN     = 16384
+	KBD        = 24576
+	// MAX_CONSTANT 是 A 指令能装下的最大值，最高位为 1 的字会被当作 C 指令
+	MAX_CONSTANT = 32767
+)
+
+// Boundary 是 RAM 中的一个分界，变量分配越过它时给出警告
+type Boundary struct {
+	Name    string
+	Address int
+}
+
+// boundaries 返回变量分配需要检查的分界，按地址从小到大排列，varLimit 为 0 时使用 STACK_BASE
+func boundaries(varLimit int) []Boundary {
+	if varLimit == 0 {
+		varLimit = STACK_BASE
+	}
+	res := []Boundary{{"the variable area limit", varLimit}}
+	if varLimit < STACK_BASE {
+		res = append(res, Boundary{"the stack", STACK_BASE})
+	}
+	res = append(res, Boundary{"the screen memory map", SCREEN}, Boundary{"the keyboard register", KBD})
+	// 变量区上界可能在屏幕或键盘之后，分配时按顺序检查，所以要排序
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Address < res[j].Address })
+	return res
+}
+
+// Usage 是程序占用的 ROM 和 RAM
+type Usage struct {
+	ROMWords int
+	ROMSize  int
+	// Variables 是变量的个数，FirstVar 和 LastVar 是变量区的地址范围
+	Variables int
+	FirstVar  int
+	LastVar   int
+	VarLimit  int
+}
+
+// Usage 统计程序的内存占用
+func (p *Program) Usage() Usage {
+	usage := Usage{ROMWords: len(p.Words()), ROMSize: ROM_SIZE, FirstVar: -1, LastVar: -1, VarLimit: p.varLimit}
+	for _, sym := range p.Symbols.Symbols() {
+		if sym.Kind != SYM_VARIABLE {
+			continue
+		}
+		usage.Variables += 1
+		if usage.FirstVar == -1 || sym.Address < usage.FirstVar {
+			usage.FirstVar = sym.Address
+		}
+		if sym.Address > usage.LastVar {
+			usage.LastVar = sym.Address
+		}
+	}
+	return usage
+}
+
+func (u Usage) String() string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("ROM: %d/%d words (%.1f%%)\n", u.ROMWords, u.ROMSize, float64(u.ROMWords)*100/float64(u.ROMSize)))
+	if u.Variables == 0 {
+		builder.WriteString("RAM: no variables\n")
+	} else {
+		builder.WriteString(fmt.Sprintf("RAM: %d variables at %d..%d, %d/%d words of the variable area\n",
+			u.Variables, u.FirstVar, u.LastVar, u.LastVar-16+1, u.VarLimit-16))
+	}
+	return builder.String()
+}
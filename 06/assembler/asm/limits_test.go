@@ -0,0 +1,47 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// variables 返回引用 n 个不同变量的源码
+func variables(n int) string {
+	builder := strings.Builder{}
+	for i := 0; i < n; i++ {
+		builder.WriteString(fmt.Sprintf("@v%d\n", i))
+	}
+	return builder.String()
+}
+
+// TestBoundaries 变量区上界在屏幕之后时，屏幕的警告也要给出
+func TestBoundaries(t *testing.T) {
+	tests := []struct {
+		varLimit int
+		count    int
+		want     []string
+	}{
+		{0, 240, nil},
+		{0, 241, []string{"reaches the variable area limit (256)"}},
+		{100, 241, []string{"v84 allocated at 100 reaches the variable area limit (100)", "v240 allocated at 256 reaches the stack (256)"}},
+		{20000, SCREEN - 16 + 1, []string{"reaches the screen memory map (16384)"}},
+		{20000, 20000 - 16 + 1, []string{"reaches the screen memory map (16384)", "reaches the variable area limit (20000)"}},
+		{SCREEN, SCREEN - 16 + 1, []string{"reaches the variable area limit (16384)", "reaches the screen memory map (16384)"}},
+	}
+	for _, test := range tests {
+		_, diags := Assemble(strings.NewReader(variables(test.count)), Options{Name: "vars.asm", VarLimit: test.varLimit})
+		if diags.HasErrors() {
+			t.Fatalf("limit %d: %v", test.varLimit, diags)
+		}
+		if len(diags) != len(test.want) {
+			t.Errorf("limit %d, %d variables: got %v, want %q", test.varLimit, test.count, diags, test.want)
+			continue
+		}
+		for i, want := range test.want {
+			if !diags[i].Warning || !strings.Contains(diags[i].Msg, want) {
+				t.Errorf("limit %d, %d variables: warning %d is %q, want %q", test.varLimit, test.count, i, diags[i].Msg, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package asm
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MODULE_SEP 分隔改名后的局部 label 中的模块和名字。IsSymbol 不接受它，所以改名后的 label 不会和源码中的符号重名
+const MODULE_SEP = "/"
+
+// scope 按模块解析符号：先找本模块的 label，再找全局的 label、预定义符号和变量
+type scope struct {
+	symTable *SymbolTable
+	// mangle 为 true 时局部 label 在符号表中记为 模块/名字，避免不同模块的同名 label 冲突
+	mangle  bool
+	globals map[string]Global
+	// locals 记录每个模块的 label 名字对应的符号表中的名字
+	locals map[string]map[string]string
+	// used 记录解析过的符号表中的名字，用来找没有用到的 label
+	used map[string]bool
+}
+
+func newScope(symTable *SymbolTable, mangle bool, globals map[string]Global) *scope {
+	return &scope{symTable: symTable, mangle: mangle, globals: globals, locals: make(map[string]map[string]string), used: make(map[string]bool)}
+}
+
+func (s *scope) defineLabel(module string, name string, address int, pos Pos) {
+	key := name
+	if g, ok := s.globals[name]; s.mangle && (!ok || g.Module != module) {
+		key = module + MODULE_SEP + name
+	}
+	if s.locals[module] == nil {
+		s.locals[module] = make(map[string]string)
+	}
+	s.locals[module][name] = key
+	s.symTable.AddLabel(key, address, pos)
+}
+
+func (s *scope) defined(module string, name string) bool {
+	_, ok := s.locals[module][name]
+	return ok
+}
+
+func (s *scope) resolve(module string, name string) (int, bool) {
+	if key, ok := s.locals[module][name]; ok {
+		name = key
+	}
+	s.used[name] = true
+	address := s.symTable.GetAddress(name)
+	return address, address != -1
+}
+
+// owner 返回把 name 定义为局部 label 的另一个模块，用来报告引用了别的模块没有导出的 label
+func (s *scope) owner(module string, name string) (string, bool) {
+	modules := make([]string, 0, len(s.locals))
+	for m := range s.locals {
+		modules = append(modules, m)
+	}
+	sort.Strings(modules)
+	for _, m := range modules {
+		if key, ok := s.locals[m][name]; ok && m != module && key != name {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+// isLabel 判断模块中的 name 是否是 label
+func (s *scope) isLabel(module string, name string) bool {
+	if key, ok := s.locals[module][name]; ok {
+		name = key
+	}
+	return s.symTable.Contains(name) && s.symTable.Kind(name) == SYM_LABEL
+}
+
+// lookup 返回某个模块中常量表达式求值用的查找函数
+func (s *scope) lookup(module string) func(string) (int, bool) {
+	return func(name string) (int, bool) {
+		return s.resolve(module, name)
+	}
+}
+
+// moduleName 用去掉扩展名的路径作为模块名，不同目录中的同名文件是不同的模块
+func moduleName(file string) string {
+	clean := filepath.ToSlash(filepath.Clean(file))
+	return strings.TrimSuffix(clean, filepath.Ext(clean))
+}
@@ -0,0 +1,58 @@
+package asm
+
+import (
+	"strings"
+	"testing"
+)
+
+func link(srcs map[string]string, order ...string) (*Program, Diagnostics) {
+	sources := make([]Source, 0, len(order))
+	for _, name := range order {
+		sources = append(sources, Source{Name: name, Reader: strings.NewReader(srcs[name])})
+	}
+	return AssembleAll(sources, Options{Name: order[0]})
+}
+
+// TestLinkSameBaseName 不同目录中的同名文件是不同的模块，局部 label 互不影响
+func TestLinkSameBaseName(t *testing.T) {
+	prog, diags := link(map[string]string{
+		"d1/u.asm": "@X\n0;JMP\n(X)\n@X\n0;JMP\n",
+		"d2/u.asm": "(X)\n@X\n0;JMP\n",
+	}, "d1/u.asm", "d2/u.asm")
+	if len(diags) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	want := []uint16{2, 0xea87, 2, 0xea87, 4, 0xea87}
+	sameWords(t, "d1/u.asm + d2/u.asm", prog.Words(), want)
+}
+
+// TestLinkMangledNames 改名后的局部 label 不会和源码中的符号（可以含 :）重名
+func TestLinkMangledNames(t *testing.T) {
+	prog, diags := link(map[string]string{
+		"lib.asm":   "(HIDDEN)\n@HIDDEN\n0;JMP\n",
+		"clash.asm": "(lib:HIDDEN)\n@lib:HIDDEN\n0;JMP\n",
+	}, "lib.asm", "clash.asm")
+	if diags.HasErrors() {
+		t.Fatalf("unexpected errors: %v", diags)
+	}
+	sameWords(t, "lib.asm + clash.asm", prog.Words(), []uint16{0, 0xea87, 2, 0xea87})
+}
+
+// TestLinkUnexportedLabel 引用别的模块没有导出的 label 报错，而不是分配成变量
+func TestLinkUnexportedLabel(t *testing.T) {
+	srcs := map[string]string{
+		"main.asm": "@HIDDEN\n0;JMP\n",
+		"lib.asm":  "(HIDDEN)\n@HIDDEN\n0;JMP\n",
+	}
+	_, diags := link(srcs, "main.asm", "lib.asm")
+	if !diags.HasErrors() || diags[0].Error() != "main.asm:1:2: HIDDEN is local to lib; export it with .global" {
+		t.Fatalf("got %v", diags)
+	}
+
+	srcs["lib.asm"] = ".global HIDDEN\n" + srcs["lib.asm"]
+	prog, diags := link(srcs, "main.asm", "lib.asm")
+	if diags.HasErrors() {
+		t.Fatalf("unexpected errors: %v", diags)
+	}
+	sameWords(t, "exported", prog.Words(), []uint16{2, 0xea87, 2, 0xea87})
+}
@@ -0,0 +1,159 @@
+package asm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 各种 lint 警告的名字，用 Options.NoWarn 可以分别关闭
+const (
+	LINT_UNUSED_LABEL  = "unused-label"
+	LINT_CASE_MISMATCH = "case-mismatch"
+	LINT_DUPLICATE     = "duplicate-label"
+	LINT_PREDEFINED    = "redefined-predefined"
+	LINT_M_AFTER_LABEL = "m-after-label"
+)
+
+// Lints 是所有 lint 警告及其说明
+var Lints = map[string]string{
+	LINT_UNUSED_LABEL:  "label is defined but never referenced",
+	LINT_CASE_MISMATCH: "new variable differs from a label or predefined symbol only by case",
+	LINT_DUPLICATE:     "label is defined more than once, the last definition wins",
+	LINT_PREDEFINED:    "label redefines a predefined symbol such as SP or KBD",
+	LINT_M_AFTER_LABEL: "C-instruction uses M right after @ of a label, which is a ROM address",
+}
+
+// ParseNoWarn 解析逗号分隔的 lint 名字，all 表示全部
+func ParseNoWarn(list string) (map[string]bool, error) {
+	disabled := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+		case name == "all":
+			for lint := range Lints {
+				disabled[lint] = true
+			}
+		case Lints[name] != "":
+			disabled[name] = true
+		default:
+			names := make([]string, 0, len(Lints))
+			for lint := range Lints {
+				names = append(names, lint)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown warning %q (want all or one of %s)", name, strings.Join(names, ", "))
+		}
+	}
+	return disabled, nil
+}
+
+// linter 在两遍分析中收集 lint 警告
+type linter struct {
+	disabled map[string]bool
+	diags    *Diagnostics
+	// labels 是定义过的 label，用来在第二遍结束后找出没有用到的
+	labels []labelDef
+	// referenced 是每个模块中 A 指令引用的符号，在优化之前记录，被优化掉的引用也算用到
+	referenced map[string]map[string]bool
+	// folded 是每个模块中 label 和预定义符号转为小写后的名字，用来找只有大小写不同的变量
+	folded map[string]map[string]string
+}
+
+type labelDef struct {
+	module string
+	name   string
+	pos    Pos
+}
+
+func newLinter(disabled map[string]bool, diags *Diagnostics) *linter {
+	return &linter{disabled: disabled, diags: diags, referenced: make(map[string]map[string]bool), folded: make(map[string]map[string]string)}
+}
+
+func (l *linter) warn(lint string, pos Pos, format string, args ...any) {
+	if l.disabled[lint] {
+		return
+	}
+	l.diags.warn(pos, "%s [%s]", fmt.Sprintf(format, args...), lint)
+}
+
+// reference 在优化之前对每条 A 指令调用，记录它引用的符号
+func (l *linter) reference(inst Instruction, parser *Parser) {
+	names := []string{parser.Symbol(inst.Text)}
+	if inst.expr != nil {
+		names = exprSymbols(inst.expr)
+	}
+	if l.referenced[inst.Module] == nil {
+		l.referenced[inst.Module] = make(map[string]bool)
+	}
+	for _, name := range names {
+		l.referenced[inst.Module][name] = true
+	}
+}
+
+// defineLabel 在第一遍中 label 加入符号表之前调用
+func (l *linter) defineLabel(s *scope, inst Instruction, name string) {
+	if IsPredefined(name) {
+		l.warn(LINT_PREDEFINED, inst.Pos, "label %s redefines a predefined symbol", name)
+	}
+	if key, ok := s.locals[inst.Module][name]; ok {
+		l.warn(LINT_DUPLICATE, inst.Pos, "label %s already defined at %s", name, s.symTable.defs[key])
+	}
+	// 宏展开出的 label 不是用户直接写的，不检查是否用到
+	if inst.Macro == "" {
+		l.labels = append(l.labels, labelDef{module: inst.Module, name: name, pos: inst.Pos})
+	}
+}
+
+// newVariable 在第二遍中分配变量时调用
+func (l *linter) newVariable(s *scope, inst Instruction, name string) {
+	folded, ok := l.folded[inst.Module]
+	if !ok {
+		folded = make(map[string]string)
+		for _, sym := range predefinedSymbols() {
+			folded[strings.ToLower(sym)] = sym
+		}
+		for label := range s.locals[inst.Module] {
+			folded[strings.ToLower(label)] = label
+		}
+		for label, g := range s.globals {
+			if g.Module != inst.Module {
+				folded[strings.ToLower(label)] = label
+			}
+		}
+		l.folded[inst.Module] = folded
+	}
+	if other, ok := folded[strings.ToLower(name)]; ok {
+		l.warn(LINT_CASE_MISMATCH, inst.at(1), "new variable %s differs from %s only by case", name, other)
+	}
+}
+
+// useM 检查用到 M 的 C 指令，prev 是它前面的一条指令
+func (l *linter) useM(s *scope, inst Instruction, prev Instruction, parser *Parser) {
+	if prev.Type != A_INSTRUCTION || !strings.Contains(parser.Comp(inst.Text)+parser.Dest(inst.Text), "M") {
+		return
+	}
+	names := []string{parser.Symbol(prev.Text)}
+	if prev.expr != nil {
+		names = exprSymbols(prev.expr)
+	}
+	for _, name := range names {
+		if s.isLabel(prev.Module, name) {
+			l.warn(LINT_M_AFTER_LABEL, inst.Pos, "M used right after @%s, but %s is a label (ROM address)", parser.Symbol(prev.Text), name)
+			return
+		}
+	}
+}
+
+// finish 在第二遍结束后报告没有用到的 label，导出的 label 算作用到
+func (l *linter) finish(s *scope) {
+	for _, def := range l.labels {
+		if g, ok := s.globals[def.name]; ok && g.Module == def.module {
+			continue
+		}
+		if !s.used[s.locals[def.module][def.name]] && !l.referenced[def.module][def.name] {
+			l.warn(LINT_UNUSED_LABEL, def.pos, "label %s is never used", def.name)
+		}
+	}
+}
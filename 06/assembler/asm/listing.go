@@ -0,0 +1,84 @@
+package asm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Listing 把每个 ROM 地址、二进制指令和源码行对应起来，并附上符号表
+type Listing struct {
+	files []File
+	// instructions 按文件名和行号索引
+	instructions map[string]map[int][]Instruction
+	symTable     *SymbolTable
+}
+
+func NewListing(prog *Program) *Listing {
+	byLine := make(map[string]map[int][]Instruction)
+	for _, inst := range prog.Instructions {
+		if byLine[inst.Pos.File] == nil {
+			byLine[inst.Pos.File] = make(map[int][]Instruction)
+		}
+		byLine[inst.Pos.File][inst.Pos.Line] = append(byLine[inst.Pos.File][inst.Pos.Line], inst)
+	}
+	return &Listing{files: prog.Files, instructions: byLine, symTable: prog.Symbols}
+}
+
+func (l *Listing) Bytes() []byte {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("%-5s  %-16s  %5s  %s\n", "ADDR", "WORD", "LINE", "SOURCE"))
+	for _, file := range l.files {
+		if len(l.files) > 1 {
+			builder.WriteString(fmt.Sprintf("\n%s\n", file.Name))
+		}
+		l.writeFile(&builder, file)
+	}
+
+	builder.WriteString("\nLABELS\n")
+	l.writeSymbols(&builder, SYM_LABEL)
+	builder.WriteString("\nVARIABLES\n")
+	l.writeSymbols(&builder, SYM_VARIABLE)
+	return []byte(builder.String())
+}
+
+func (l *Listing) writeFile(builder *strings.Builder, file File) {
+	for i, raw := range file.Lines {
+		raw = strings.TrimRight(raw, "\r")
+		insts := l.instructions[file.Name][i+1]
+		addr, word := "", ""
+		if len(insts) == 1 && insts[0].Macro == "" {
+			addr = fmt.Sprintf("%05d", insts[0].Address)
+			word = insts[0].Code
+		}
+		builder.WriteString(fmt.Sprintf("%-5s  %-16s  %5d  %s\n", addr, word, i+1, raw))
+		if addr != "" {
+			continue
+		}
+		// 宏调用：逐条列出展开后的指令
+		for _, inst := range insts {
+			addr = fmt.Sprintf("%05d", inst.Address)
+			builder.WriteString(fmt.Sprintf("%-5s  %-16s  %5s  %*s+ %s\n", addr, inst.Code, "", strings.Index(raw, strings.TrimSpace(raw)), "", inst.Text))
+		}
+	}
+}
+
+// writeSymbols 按地址顺序输出某一类符号及其定义（变量为第一次使用）的行号
+func (l *Listing) writeSymbols(builder *strings.Builder, kind string) {
+	names := make([]string, 0)
+	for name, k := range l.symTable.kinds {
+		if k == kind {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ai, aj := l.symTable.GetAddress(names[i]), l.symTable.GetAddress(names[j])
+		if ai != aj {
+			return ai < aj
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		builder.WriteString(fmt.Sprintf("%05d  %-24s  line %d\n", l.symTable.GetAddress(name), name, l.symTable.defs[name].Line))
+	}
+}
@@ -0,0 +1,144 @@
+package asm
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxMacroDepth 限制宏嵌套展开的层数，防止递归定义的宏无限展开
+const maxMacroDepth = 64
+
+// Macro 是 .macro NAME a, b ... .endm 定义的宏，宏体中用 \a 引用参数，\@ 是每次展开唯一的编号
+type Macro struct {
+	Name   string
+	Params []string
+	Body   []string
+	Pos    Pos
+}
+
+// stdMacros 是内置的伪指令，用户可以用同名宏覆盖
+const stdMacros = `
+.macro PUSHD
+@SP
+AM=M+1
+A=A-1
+M=D
+.endm
+
+.macro POPD
+@SP
+AM=M-1
+D=M
+.endm
+
+.macro LOADI reg, value
+@\value
+\reg=A
+.endm
+
+.macro JMP target
+@\target
+0;JMP
+.endm
+
+.macro INC reg
+\reg=\reg+1
+.endm
+
+.macro DEC reg
+\reg=\reg-1
+.endm
+`
+
+var builtinMacros = stdMacroTable()
+
+// stdMacroTable 解析内置伪指令
+func stdMacroTable() map[string]*Macro {
+	parser := newParser(strings.NewReader(stdMacros), "<builtin>", make(map[string]*Macro))
+	var diags Diagnostics
+	parser.Parse(&diags)
+	if len(diags) > 0 {
+		panic("asm: invalid builtin macros: " + diags[0].Error())
+	}
+	return parser.macros
+}
+
+// beginMacro 解析 ".macro NAME a, b" 这一行
+func (p *Parser) beginMacro(line string, pos Pos, diags *Diagnostics) {
+	fields := strings.Fields(strings.TrimPrefix(line, ".macro"))
+	if len(fields) == 0 {
+		diags.add(pos, "missing macro name")
+		fields = []string{""}
+	}
+	macro := &Macro{Name: fields[0], Pos: pos}
+	if macro.Name != "" && !IsSymbol(macro.Name) {
+		diags.add(pos, "invalid macro name %q", macro.Name)
+	}
+	for _, param := range splitArgs(strings.Join(fields[1:], " ")) {
+		if !IsSymbol(param) {
+			diags.add(pos, "invalid macro parameter %q", param)
+		}
+		macro.Params = append(macro.Params, param)
+	}
+	p.defining = macro
+}
+
+// endMacro 结束当前的宏定义
+func (p *Parser) endMacro(diags *Diagnostics) {
+	macro := p.defining
+	p.defining = nil
+	if macro.Name == "" {
+		return
+	}
+	if prev, ok := p.macros[macro.Name]; ok && prev.Pos.File != "<builtin>" {
+		diags.add(macro.Pos, "macro %s already defined at %s", macro.Name, prev.Pos)
+		return
+	}
+	p.macros[macro.Name] = macro
+}
+
+// invocation 判断 line 是否是宏调用，是则返回宏和参数
+func (p *Parser) invocation(line string) (*Macro, []string, bool) {
+	fields := strings.Fields(line)
+	macro, ok := p.macros[fields[0]]
+	if !ok {
+		return nil, nil, false
+	}
+	return macro, splitArgs(line[len(fields[0]):]), true
+}
+
+// expand 返回宏展开后的各行
+func (p *Parser) expand(macro *Macro, args []string) []string {
+	p.expansions += 1
+	params := make([]string, len(macro.Params))
+	copy(params, macro.Params)
+	// 先替换长的参数名，避免 \ab 被当成 \a 加 b
+	sort.Slice(params, func(i, j int) bool { return len(params[i]) > len(params[j]) })
+	values := make(map[string]string, len(args))
+	for i, param := range macro.Params {
+		values[param] = args[i]
+	}
+
+	lines := make([]string, 0, len(macro.Body))
+	for _, line := range macro.Body {
+		for _, param := range params {
+			line = strings.ReplaceAll(line, `\`+param, values[param])
+		}
+		line = strings.ReplaceAll(line, `\@`, strconv.Itoa(p.expansions))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitArgs 分割参数：有逗号时按逗号分割（参数中可以有空格，如 SCREEN + 32），否则按空白分割
+func splitArgs(s string) []string {
+	if !strings.Contains(s, ",") {
+		return strings.Fields(s)
+	}
+	args := make([]string, 0)
+	for _, arg := range strings.Split(s, ",") {
+		args = append(args, strings.TrimSpace(arg))
+	}
+	return args
+}
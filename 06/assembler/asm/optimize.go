@@ -0,0 +1,159 @@
+package asm
+
+// optimizer 是在编码之前对指令流做的窥孔优化，只删除指令，不删除也不移动 label，
+// 所以 label 仍然指向原来的那条指令（或其后第一条保留下来的指令）
+type optimizer struct {
+	parser *Parser
+}
+
+// Optimize 反复执行各个优化直到没有变化，返回优化后的指令和省掉的指令数。
+// 用 label 做算术的表达式（如 @LOOP+3）和跳到常量地址（如 PongL.asm 中的 @133 0;JMP）都依赖指令的位置，
+// 这时不做优化，ok 为 false
+func Optimize(instructions []Instruction) (res []Instruction, saved int, ok bool) {
+	o := &optimizer{parser: NewParser(nil, "")}
+	labels := make(map[string]bool)
+	for _, inst := range instructions {
+		if inst.Type == L_INSTRUCTION {
+			labels[o.parser.Symbol(inst.Text)] = true
+		}
+	}
+	for i, inst := range instructions {
+		if inst.expr == nil {
+			continue
+		}
+		if i+1 < len(instructions) && instructions[i+1].Type == C_INSTRUCTION {
+			if _, _, jump := o.fields(instructions[i+1]); jump != "" {
+				return instructions, 0, false
+			}
+		}
+		for _, name := range exprSymbols(inst.expr) {
+			if labels[name] {
+				return instructions, 0, false
+			}
+		}
+	}
+
+	before := countCode(instructions)
+	for {
+		n := len(instructions)
+		instructions = o.foldSP(instructions)
+		instructions = o.dropJumpToNext(instructions)
+		instructions = o.dropRedundantLoads(instructions)
+		if len(instructions) == n {
+			break
+		}
+	}
+	return instructions, before - countCode(instructions), true
+}
+
+func countCode(instructions []Instruction) int {
+	n := 0
+	for _, inst := range instructions {
+		if inst.Type != L_INSTRUCTION {
+			n += 1
+		}
+	}
+	return n
+}
+
+// isA 判断是否为 @sym
+func (o *optimizer) isA(inst Instruction, sym string) bool {
+	return inst.Type == A_INSTRUCTION && o.parser.Symbol(inst.Text) == sym
+}
+
+// fields 返回规范化后的 dest、comp、jump
+func (o *optimizer) fields(inst Instruction) (string, string, string) {
+	f := LexC(inst.Text)
+	return NormalizeDest(f.Dest.Text), NormalizeComp(f.Comp.Text), f.Jump.Text
+}
+
+func (o *optimizer) isC(inst Instruction, dest string, comp string, jump string) bool {
+	if inst.Type != C_INSTRUCTION {
+		return false
+	}
+	d, c, j := o.fields(inst)
+	return d == dest && c == comp && j == jump
+}
+
+// foldSP 把相互抵消的 @SP M=M+1 @SP M=M-1（或先减后加）合并为 @SP，A 的值和原来一样
+func (o *optimizer) foldSP(instructions []Instruction) []Instruction {
+	res := make([]Instruction, 0, len(instructions))
+	for i := 0; i < len(instructions); i++ {
+		if i+3 < len(instructions) && o.isA(instructions[i], "SP") && o.isA(instructions[i+2], "SP") &&
+			(o.isC(instructions[i+1], "M", "M+1", "") && o.isC(instructions[i+3], "M", "M-1", "") ||
+				o.isC(instructions[i+1], "M", "M-1", "") && o.isC(instructions[i+3], "M", "M+1", "")) {
+			res = append(res, instructions[i])
+			i += 3
+			continue
+		}
+		res = append(res, instructions[i])
+	}
+	return res
+}
+
+// dropJumpToNext 删除跳到下一条指令的 @L 和跳转。
+// 只有 label 后面是 A 指令（会覆盖 A）时才删除，因为跳转后 A 的值本来是 L 的地址
+func (o *optimizer) dropJumpToNext(instructions []Instruction) []Instruction {
+	res := make([]Instruction, 0, len(instructions))
+	for i := 0; i < len(instructions); i++ {
+		if i+1 < len(instructions) && instructions[i].Type == A_INSTRUCTION && instructions[i+1].Type == C_INSTRUCTION {
+			target := o.parser.Symbol(instructions[i].Text)
+			dest, _, jump := o.fields(instructions[i+1])
+			if dest == "" && jump != "" && o.jumpsToNext(instructions, i+2, target, instructions[i].Module) {
+				i += 1
+				continue
+			}
+		}
+		res = append(res, instructions[i])
+	}
+	return res
+}
+
+// jumpsToNext 判断从 start 开始的连续 label 中是否有 target，且这些 label 之后是 A 指令
+func (o *optimizer) jumpsToNext(instructions []Instruction, start int, target string, module string) bool {
+	found := false
+	i := start
+	for ; i < len(instructions) && instructions[i].Type == L_INSTRUCTION; i++ {
+		if o.parser.Symbol(instructions[i].Text) == target && instructions[i].Module == module {
+			found = true
+		}
+	}
+	return found && i < len(instructions) && instructions[i].Type == A_INSTRUCTION
+}
+
+// dropRedundantLoads 删除重复的 @X，以及重复的 @X A=M。
+// 记录 A 中已知的内容：@X 之后是 X 的地址，@X A=M 之后是 RAM[X]。遇到 label、跳转或写 A 的指令时清空。
+// 假设写 M 不会改写 RAM[X] 本身，也就是 X（如 SP）不指向自己
+func (o *optimizer) dropRedundantLoads(instructions []Instruction) []Instruction {
+	res := make([]Instruction, 0, len(instructions))
+	state := ""
+	for i := 0; i < len(instructions); i++ {
+		inst := instructions[i]
+		switch inst.Type {
+		case L_INSTRUCTION:
+			state = ""
+		case A_INSTRUCTION:
+			sym := o.parser.Symbol(inst.Text)
+			if state == "@"+sym {
+				continue
+			}
+			if state == "*"+sym && i+1 < len(instructions) && o.isC(instructions[i+1], "A", "M", "") {
+				i += 1
+				continue
+			}
+			state = "@" + sym
+		case C_INSTRUCTION:
+			dest, comp, jump := o.fields(inst)
+			switch {
+			case jump != "":
+				state = ""
+			case dest == "A" && comp == "M" && len(state) > 0 && state[0] == '@':
+				state = "*" + state[1:]
+			case len(dest) > 0 && dest[0] == 'A':
+				state = ""
+			}
+		}
+		res = append(res, inst)
+	}
+	return res
+}
@@ -0,0 +1,167 @@
+package asm
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// hack 是测试用的最小 Hack 计算机，汇编器不能依赖模拟器模块（模拟器依赖汇编器）
+type hack struct {
+	rom  []uint16
+	ram  [32768]int16
+	a, d int16
+	pc   int
+	// screen 是写入屏幕的内容，按顺序记录
+	screen []write
+}
+
+// write 是一次内存写入
+type write struct {
+	address int16
+	value   int16
+}
+
+// alu 按 zx nx zy ny f no 六位计算 comp
+func alu(x int16, y int16, c uint16) int16 {
+	if c&0b100000 != 0 {
+		x = 0
+	}
+	if c&0b010000 != 0 {
+		x = ^x
+	}
+	if c&0b001000 != 0 {
+		y = 0
+	}
+	if c&0b000100 != 0 {
+		y = ^y
+	}
+	var out int16
+	if c&0b000010 != 0 {
+		out = x + y
+	} else {
+		out = x & y
+	}
+	if c&0b000001 != 0 {
+		out = ^out
+	}
+	return out
+}
+
+// run 执行 budget 条指令
+func (h *hack) run(budget int) {
+	for ; budget > 0 && h.pc < len(h.rom); budget-- {
+		w := h.rom[h.pc]
+		if w&0x8000 == 0 {
+			h.a = int16(w)
+			h.pc += 1
+			continue
+		}
+		address := uint16(h.a) & 0x7fff
+		y := h.a
+		if w&0x1000 != 0 {
+			y = h.ram[address]
+		}
+		out := alu(h.d, y, w>>6&0x3f)
+		if w&0b001000 != 0 {
+			h.ram[address] = out
+			if address >= SCREEN && address < KBD {
+				h.screen = append(h.screen, write{int16(address), out})
+			}
+		}
+		jump := w&0b100 != 0 && out < 0 || w&0b010 != 0 && out == 0 || w&0b001 != 0 && out > 0
+		if w&0b100000 != 0 {
+			h.a = out
+		}
+		if w&0b010000 != 0 {
+			h.d = out
+		}
+		if jump {
+			h.pc = int(address)
+		} else {
+			h.pc += 1
+		}
+	}
+}
+
+// TestOptimizeCoursePrograms 课程程序优化前后的输出相同：不用屏幕的程序（如 Mult）最后停在死循环中，比较 RAM；
+// 用屏幕的程序（如 Pong）比较写入屏幕的内容。返回地址是 ROM 地址，优化后本来就会变，所以不比较其它写入
+func TestOptimizeCoursePrograms(t *testing.T) {
+	for _, file := range coursePrograms(t) {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var runs [2]*hack
+		for i, optimize := range []bool{false, true} {
+			prog := assemble(t, file, string(src), Options{Optimize: optimize})
+			h := &hack{rom: prog.Words()}
+			// Mult、Max、Rect 从 RAM[0] 和 RAM[1] 读取输入
+			h.ram[0], h.ram[1] = 6, 7
+			h.run(5000000)
+			runs[i] = h
+		}
+		plain, optimized := runs[0], runs[1]
+		if len(plain.screen) == 0 {
+			if optimized.ram != plain.ram {
+				t.Errorf("%s: RAM differs after optimization", file)
+			}
+			continue
+		}
+		// 优化后的程序在相同的指令数内走得更远，比较共同的部分
+		n := len(plain.screen)
+		if len(optimized.screen) < n {
+			t.Fatalf("%s: %d screen writes after optimization, %d before", file, len(optimized.screen), n)
+		}
+		for i := 0; i < n; i++ {
+			if optimized.screen[i] != plain.screen[i] {
+				t.Fatalf("%s: screen write %d is %v after optimization, want %v", file, i, optimized.screen[i], plain.screen[i])
+			}
+		}
+	}
+}
+
+// TestOptimize 检查各个优化删掉的指令
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		src   string
+		want  string
+		saved int
+	}{
+		{"@SP\nM=M+1\n@SP\nM=M-1\nD=0\n", "@SP\nD=0", 3},
+		{"@SP\nM=M-1\n@SP\nM=M+1\n", "@SP", 3},
+		{"@NEXT\n0;JMP\n(NEXT)\n@0\n", "(NEXT)\n@0", 2},
+		// label 后面不是 A 指令时，跳转后 A 的值会被用到
+		{"@NEXT\n0;JMP\n(NEXT)\nD=A\n", "@NEXT\n0;JMP\n(NEXT)\nD=A", 0},
+		{"@X\nD=M\n@X\nM=D+1\n", "@X\nD=M\nM=D+1", 1},
+		{"@SP\nA=M\nD=M\n@SP\nA=M\nM=D\n", "@SP\nA=M\nD=M\nM=D", 2},
+		// label 可能是跳转目标，A 的内容不再已知
+		{"@X\nD=M\n(L)\n@X\nM=D\n", "@X\nD=M\n(L)\n@X\nM=D", 0},
+		{"@X\nD;JGT\n@X\nM=D\n", "@X\nD;JGT\n@X\nM=D", 0},
+	}
+	for _, test := range tests {
+		instructions := NewParser(strings.NewReader(test.src), "t.asm").Parse(&Diagnostics{})
+		res, saved, ok := Optimize(instructions)
+		texts := make([]string, 0, len(res))
+		for _, inst := range res {
+			texts = append(texts, inst.Text)
+		}
+		if got := strings.Join(texts, "\n"); !ok || got != test.want || saved != test.saved {
+			t.Errorf("Optimize(%q) = %q, saved %d, want %q, saved %d", test.src, got, saved, test.want, test.saved)
+		}
+	}
+	// 指令的位置被用到时不做优化
+	for _, src := range []string{"(L)\n@L+2\n0;JMP\n", "@SP\nM=M+1\n@SP\nM=M-1\n@4\n0;JMP\n"} {
+		if _, _, ok := Optimize(NewParser(strings.NewReader(src), "t.asm").Parse(&Diagnostics{})); ok {
+			t.Errorf("Optimize(%q): expected optimization to be skipped", src)
+		}
+	}
+}
+
+// TestOptimizeLabelUses 被优化掉的引用也算用到了 label，不能报 unused-label
+func TestOptimizeLabelUses(t *testing.T) {
+	prog, diags := Assemble(strings.NewReader("@NEXT\n0;JMP\n(NEXT)\n@0\nD=M\n"), Options{Name: "next.asm", Optimize: true})
+	if len(diags) != 0 || prog.Saved != 2 {
+		t.Errorf("saved %d instructions with diagnostics %v, want 2 and none", prog.Saved, diags)
+	}
+}
@@ -0,0 +1,126 @@
+package asm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FORMAT_HACK = "hack"
+	FORMAT_BIN  = "bin"
+	FORMAT_IHEX = "ihex"
+	FORMAT_MEMB = "memb"
+	FORMAT_MEMH = "memh"
+)
+
+// formatWriters 是各种输出格式的写入函数
+var formatWriters = map[string]func(w io.Writer, words []uint16) error{
+	FORMAT_HACK: writeHackWords,
+	FORMAT_BIN:  writeBin,
+	FORMAT_IHEX: writeIntelHex,
+	FORMAT_MEMB: writeMemb,
+	FORMAT_MEMH: writeMemh,
+}
+
+// formatExts 是输出文件扩展名对应的格式
+var formatExts = map[string]string{
+	".hack": FORMAT_HACK,
+	".bin":  FORMAT_BIN,
+	".hex":  FORMAT_IHEX,
+	".ihex": FORMAT_IHEX,
+	".memb": FORMAT_MEMB,
+	".mem":  FORMAT_MEMH,
+	".memh": FORMAT_MEMH,
+}
+
+// FormatForFile 根据扩展名推断输出格式
+func FormatForFile(name string) (string, bool) {
+	format, ok := formatExts[strings.ToLower(filepath.Ext(name))]
+	return format, ok
+}
+
+// WriteFormat 以指定格式输出程序：hack 文本、bin 大端二进制、ihex Intel HEX，
+// memb/memh 分别是 Verilog $readmemb/$readmemh 能读取的二进制和十六进制文本
+func (p *Program) WriteFormat(w io.Writer, format string) error {
+	write, ok := formatWriters[format]
+	if !ok {
+		return fmt.Errorf("unknown output format %q (want hack, bin, ihex, memb or memh)", format)
+	}
+	bw := bufio.NewWriter(w)
+	err := write(bw, p.Words())
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func writeHackWords(w io.Writer, words []uint16) error {
+	for _, word := range words {
+		_, err := fmt.Fprintf(w, "%016b\n", word)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBin(w io.Writer, words []uint16) error {
+	return binary.Write(w, binary.BigEndian, words)
+}
+
+func writeMemb(w io.Writer, words []uint16) error {
+	_, err := fmt.Fprintf(w, "// %d words, $readmemb\n", len(words))
+	if err != nil {
+		return err
+	}
+	return writeHackWords(w, words)
+}
+
+func writeMemh(w io.Writer, words []uint16) error {
+	_, err := fmt.Fprintf(w, "// %d words, $readmemh\n", len(words))
+	if err != nil {
+		return err
+	}
+	for _, word := range words {
+		_, err := fmt.Fprintf(w, "%04x\n", word)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeIntelHex 输出 Intel HEX，每条记录 16 字节，地址按字节计算，每个字高位在前
+func writeIntelHex(w io.Writer, words []uint16) error {
+	data := make([]byte, len(words)*2)
+	for i, word := range words {
+		binary.BigEndian.PutUint16(data[i*2:], word)
+	}
+	for addr := 0; addr < len(data); addr += 16 {
+		end := addr + 16
+		if end > len(data) {
+			end = len(data)
+		}
+		err := writeHexRecord(w, addr, 0x00, data[addr:end])
+		if err != nil {
+			return err
+		}
+	}
+	return writeHexRecord(w, 0, 0x01, nil)
+}
+
+func writeHexRecord(w io.Writer, addr int, typ byte, data []byte) error {
+	record := []byte{byte(len(data)), byte(addr >> 8), byte(addr), typ}
+	record = append(record, data...)
+	var sum byte
+	for _, b := range record {
+		sum += b
+	}
+	record = append(record, -sum)
+	_, err := fmt.Fprintf(w, ":%X\n", record)
+	return err
+}
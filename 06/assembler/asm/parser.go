@@ -0,0 +1,322 @@
+package asm
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const (
+	A_INSTRUCTION = "A"
+	C_INSTRUCTION = "C"
+	L_INSTRUCTION = "L"
+)
+
+// Parser 逐行读取汇编源码
+type Parser struct {
+	name    string
+	scanner *bufio.Scanner
+	// Lines 是已经读到的源码行，用于生成 listing
+	Lines []string
+	// Files 是本文件及其 .include 的文件，Parse 结束后才完整
+	Files []File
+	// Globals 是 .global 导出的 label
+	Globals []Global
+	// macros 是已定义的宏（包括内置伪指令），defining 是正在定义的宏
+	macros     map[string]*Macro
+	defining   *Macro
+	expansions int
+	// module 是指令所属的模块，label 默认只在模块内可见；open 用来打开 .include 的文件
+	module   string
+	open     func(name string) (io.ReadCloser, error)
+	included []File
+	// includeStack 是正在 include 本文件的文件，用于检测循环 include
+	includeStack []string
+}
+
+// File 是一个源文件的全部行
+type File struct {
+	Name  string
+	Lines []string
+}
+
+// Global 是一条 .global 声明
+type Global struct {
+	Name   string
+	Module string
+	Pos    Pos
+}
+
+// Instruction 是去掉空白和注释后的一条指令，Pos 指向它在源文件中的起始位置
+type Instruction struct {
+	Type string
+	Text string
+	Pos  Pos
+	// Address 是指令在 ROM 中的地址（label 则是它指向的地址），Code 是编码后的二进制
+	Address int
+	Code    string
+	// Macro 是展开出这条指令的宏，直接写在源码中的指令为空
+	Macro string
+	// Module 是指令所属的模块，即传给汇编器的源文件（.include 的文件属于包含它的模块）
+	Module string
+	// expr 是 A 指令的常量表达式，操作数是单个符号时为 nil
+	expr Expr
+}
+
+// at 返回指令内第 offset 个字符的位置
+func (i Instruction) at(offset int) Pos {
+	pos := i.Pos
+	pos.Col += offset
+	return pos
+}
+
+// NewParser 从 r 读取源码，name 只用于报错信息
+func NewParser(r io.Reader, name string) *Parser {
+	macros := make(map[string]*Macro, len(builtinMacros))
+	for name, macro := range builtinMacros {
+		macros[name] = macro
+	}
+	return newParser(r, name, macros)
+}
+
+func newParser(r io.Reader, name string, macros map[string]*Macro) *Parser {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return &Parser{name: name, scanner: scanner, macros: macros}
+}
+
+// Parse 跳过空行和注释行，收集宏定义并展开宏调用，检查 A 指令和 label 的格式，返回带位置信息的指令
+func (p *Parser) Parse(diags *Diagnostics) []Instruction {
+	instructions := make([]Instruction, 0)
+	for p.scanner.Scan() {
+		raw := p.scanner.Text()
+		p.Lines = append(p.Lines, raw)
+		line := strings.TrimSpace(stripComment(raw))
+		if len(line) == 0 {
+			continue
+		}
+		pos := Pos{File: p.name, Line: len(p.Lines), Col: strings.Index(raw, line) + 1}
+		if p.defining != nil {
+			if line == ".endm" {
+				p.endMacro(diags)
+			} else {
+				p.defining.Body = append(p.defining.Body, line)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ".macro") {
+			p.beginMacro(line, pos, diags)
+			continue
+		}
+		if line == ".endm" {
+			diags.add(pos, ".endm without .macro")
+			continue
+		}
+		instructions = p.parseLine(instructions, line, pos, "", 0, diags)
+	}
+	if err := p.scanner.Err(); err != nil {
+		diags.add(Pos{File: p.name, Line: len(p.Lines) + 1, Col: 1}, "%v", err)
+	}
+	if p.defining != nil {
+		diags.add(p.defining.Pos, "missing .endm for macro %s", p.defining.Name)
+		p.defining = nil
+	}
+	p.Files = append([]File{{Name: p.name, Lines: p.Lines}}, p.included...)
+	return instructions
+}
+
+// parseLine 解析一行指令追加到 instructions，宏调用会被递归展开，展开出的指令都指向调用处
+func (p *Parser) parseLine(instructions []Instruction, line string, pos Pos, macro string, depth int, diags *Diagnostics) []Instruction {
+	if m, args, ok := p.invocation(line); ok {
+		if depth >= maxMacroDepth {
+			diags.add(pos, "macro %s nested too deeply", m.Name)
+			return instructions
+		}
+		if len(args) != len(m.Params) {
+			diags.add(pos, "macro %s expects %d arguments, got %d", m.Name, len(m.Params), len(args))
+			return instructions
+		}
+		for _, expanded := range p.expand(m, args) {
+			instructions = p.parseLine(instructions, expanded, pos, m.Name, depth+1, diags)
+		}
+		return instructions
+	}
+
+	if strings.HasPrefix(line, ".") {
+		return p.directive(instructions, line, pos, diags)
+	}
+
+	inst := Instruction{
+		Type:   p.InstructionType(line),
+		Text:   line,
+		Pos:    pos,
+		Macro:  macro,
+		Module: p.module,
+	}
+	switch inst.Type {
+	case A_INSTRUCTION:
+		p.checkA(&inst, diags)
+	case L_INSTRUCTION:
+		p.checkL(inst, diags)
+	}
+	return append(instructions, inst)
+}
+
+// directive 处理 .include 和 .global
+func (p *Parser) directive(instructions []Instruction, line string, pos Pos, diags *Diagnostics) []Instruction {
+	fields := strings.Fields(line)
+	arg := strings.TrimSpace(line[len(fields[0]):])
+	switch fields[0] {
+	case ".include":
+		return append(instructions, p.include(arg, pos, diags)...)
+	case ".global":
+		names := splitArgs(arg)
+		if len(names) == 0 {
+			diags.add(pos, "missing label name after .global")
+		}
+		for _, name := range names {
+			if !IsSymbol(name) {
+				diags.add(pos, "invalid label name %q", name)
+				continue
+			}
+			p.Globals = append(p.Globals, Global{Name: name, Module: p.module, Pos: pos})
+		}
+	default:
+		diags.add(pos, "unknown directive %s", fields[0])
+	}
+	return instructions
+}
+
+// include 解析 .include "file.asm"，相对路径相对于当前文件所在的目录
+func (p *Parser) include(arg string, pos Pos, diags *Diagnostics) []Instruction {
+	if len(arg) < 2 || arg[0] != '"' || arg[len(arg)-1] != '"' {
+		diags.add(pos, "expected .include \"file\"")
+		return nil
+	}
+	if p.open == nil {
+		diags.add(pos, ".include is not supported without a file opener")
+		return nil
+	}
+	name := arg[1 : len(arg)-1]
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(p.name), name)
+	}
+	for _, including := range append(p.includeStack, p.name) {
+		if including == name {
+			diags.add(pos, "%s includes itself", name)
+			return nil
+		}
+	}
+	r, err := p.open(name)
+	if err != nil {
+		diags.add(pos, "%v", err)
+		return nil
+	}
+	defer r.Close()
+
+	sub := newParser(r, name, p.macros)
+	sub.module = p.module
+	sub.open = p.open
+	sub.includeStack = append(append([]string{}, p.includeStack...), p.name)
+	sub.expansions = p.expansions
+	instructions := sub.Parse(diags)
+	p.expansions = sub.expansions
+	p.included = append(p.included, sub.Files...)
+	p.Globals = append(p.Globals, sub.Globals...)
+	return instructions
+}
+
+// checkA 检查 A 指令的操作数。不是单个符号的操作数按常量表达式解析，在第二遍中求值
+func (p *Parser) checkA(inst *Instruction, diags *Diagnostics) {
+	sym := p.Symbol(inst.Text)
+	if len(sym) == 0 {
+		diags.add(inst.Pos, "missing A-instruction operand")
+		return
+	}
+	if IsSymbol(sym) {
+		return
+	}
+	e, err := ParseExpr(sym)
+	if err != nil {
+		diags.add(inst.at(strings.Index(inst.Text, sym)+err.(*ExprError).Offset), "%v", err)
+		return
+	}
+	inst.expr = e
+}
+
+// validLabel 判断 label 是否通过了 checkL，出错的 label 不定义，免得再多报一个未使用的警告
+func (p *Parser) validLabel(line string) bool {
+	return strings.HasSuffix(line, ")") && IsSymbol(p.Symbol(line))
+}
+
+func (p *Parser) checkL(inst Instruction, diags *Diagnostics) {
+	if !strings.HasSuffix(inst.Text, ")") {
+		diags.add(inst.Pos, "malformed label %q: missing ')'", inst.Text)
+		return
+	}
+	sym := p.Symbol(inst.Text)
+	if len(sym) == 0 {
+		diags.add(inst.Pos, "empty label")
+	} else if !IsSymbol(sym) {
+		diags.add(inst.at(strings.Index(inst.Text, sym)), "invalid label name %q", sym)
+	}
+}
+
+// IsSymbol 判断是否为合法符号：字母、数字、_ . $ :，且不以数字开头
+func IsSymbol(sym string) bool {
+	if len(sym) == 0 || unicode.IsDigit(rune(sym[0])) {
+		return false
+	}
+	for _, r := range sym {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_.$:", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Parser) InstructionType(line string) string {
+	line = strings.TrimSpace(line)
+	if line[0] == '@' {
+		return A_INSTRUCTION
+	}
+	if line[0] == '(' {
+		return L_INSTRUCTION
+	}
+	return C_INSTRUCTION
+}
+
+// Symbol 返回 A 指令的操作数或 label 的名字，去掉两边的空白
+func (p *Parser) Symbol(line string) string {
+	typ := p.InstructionType(line)
+	if typ == A_INSTRUCTION {
+		return strings.TrimSpace(line[1:])
+	} else if typ == L_INSTRUCTION {
+		return strings.TrimSpace(strings.TrimSuffix(line[1:], ")"))
+	}
+
+	return ""
+}
+
+func (p *Parser) Dest(line string) string {
+	return LexC(line).Dest.Text
+}
+
+func (p *Parser) Comp(line string) string {
+	return LexC(line).Comp.Text
+}
+
+func (p *Parser) Jump(line string) string {
+	return LexC(line).Jump.Text
+}
+
+// CompIndex 和 JumpIndex 返回对应字段在指令中的偏移，用于报错定位
+func (p *Parser) CompIndex(line string) int {
+	return LexC(line).Comp.Offset
+}
+
+func (p *Parser) JumpIndex(line string) int {
+	return LexC(line).Jump.Offset
+}
@@ -0,0 +1,207 @@
+package asm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	SYM_LABEL      = "label"
+	SYM_VARIABLE   = "variable"
+	SYM_PREDEFINED = "predefined"
+)
+
+// Symbol 是 .sym 文件中的一项
+type Symbol struct {
+	Name    string `json:"name"`
+	Address int    `json:"address"`
+	Kind    string `json:"kind"`
+}
+
+type SymbolTable struct {
+	st      map[string]int
+	nextVar int
+	// kinds 和 defs 记录 label 和变量的类型及定义位置，预定义符号不在其中
+	kinds map[string]string
+	defs  map[string]Pos
+}
+
+func NewSymbolTable() *SymbolTable {
+	st := make(map[string]int)
+	return &SymbolTable{st: st, kinds: make(map[string]string), defs: make(map[string]Pos)}
+}
+
+// Init 加入 R0-R15、SP、SCREEN 等预定义符号
+func (s *SymbolTable) Init() {
+	s.AddEntry("R0", 0)
+	s.AddEntry("R1", 1)
+	s.AddEntry("R2", 2)
+	s.AddEntry("R3", 3)
+	s.AddEntry("R4", 4)
+	s.AddEntry("R5", 5)
+	s.AddEntry("R6", 6)
+	s.AddEntry("R7", 7)
+	s.AddEntry("R8", 8)
+	s.AddEntry("R9", 9)
+	s.AddEntry("R10", 10)
+	s.AddEntry("R11", 11)
+	s.AddEntry("R12", 12)
+	s.AddEntry("R13", 13)
+	s.AddEntry("R14", 14)
+	s.AddEntry("R15", 15)
+	s.AddEntry("SP", 0)
+	s.AddEntry("LCL", 1)
+	s.AddEntry("ARG", 2)
+	s.AddEntry("THIS", 3)
+	s.AddEntry("THAT", 4)
+	s.AddEntry("SCREEN", 16384)
+	s.AddEntry("KBD", 24576)
+}
+
+func (s *SymbolTable) AddEntry(symbol string, address int) {
+	s.st[symbol] = address
+}
+
+func (s *SymbolTable) AddLabel(symbol string, address int, pos Pos) {
+	s.AddEntry(symbol, address)
+	s.kinds[symbol] = SYM_LABEL
+	s.defs[symbol] = pos
+}
+
+// AddVariable 记录变量，pos 是第一次使用它的位置
+func (s *SymbolTable) AddVariable(symbol string, address int, pos Pos) {
+	s.AddEntry(symbol, address)
+	s.kinds[symbol] = SYM_VARIABLE
+	s.defs[symbol] = pos
+}
+
+func (s *SymbolTable) Kind(symbol string) string {
+	kind, ok := s.kinds[symbol]
+	if !ok {
+		return SYM_PREDEFINED
+	}
+	return kind
+}
+
+func (s *SymbolTable) Contains(symbol string) bool {
+	_, ok := s.st[symbol]
+	return ok
+}
+
+func (s *SymbolTable) GetAddress(symbol string) int {
+	address, ok := s.st[symbol]
+	if !ok {
+		return -1
+	}
+	return address
+}
+
+// Symbols 返回符号表中的全部符号，按地址和名字排序
+func (s *SymbolTable) Symbols() []Symbol {
+	symbols := make([]Symbol, 0, len(s.st))
+	for name, address := range s.st {
+		symbols = append(symbols, Symbol{Name: name, Address: address, Kind: s.Kind(name)})
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].Address != symbols[j].Address {
+			return symbols[i].Address < symbols[j].Address
+		}
+		return symbols[i].Name < symbols[j].Name
+	})
+	return symbols
+}
+
+// FormatSymbols 把符号编码为 "text"（每行 name address kind）或 "json" 格式
+func FormatSymbols(symbols []Symbol, format string) ([]byte, error) {
+	switch format {
+	case "text":
+		builder := strings.Builder{}
+		for _, sym := range symbols {
+			builder.WriteString(fmt.Sprintf("%s %d %s\n", sym.Name, sym.Address, sym.Kind))
+		}
+		return []byte(builder.String()), nil
+	case "json":
+		content, err := json.MarshalIndent(symbols, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(content, '\n'), nil
+	}
+	return nil, fmt.Errorf("unknown symbol format %q (want text or json)", format)
+}
+
+// ReadSymbols 读取符号文件，支持 JSON 和 "name address [kind]" 文本格式，文本中的空行和 // 注释会被忽略
+func ReadSymbols(r io.Reader) ([]Symbol, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := strings.TrimSpace(string(content)); strings.HasPrefix(trimmed, "[") {
+		symbols := make([]Symbol, 0)
+		err = json.Unmarshal(content, &symbols)
+		if err != nil {
+			return nil, err
+		}
+		for _, sym := range symbols {
+			if !validKind(sym.Kind) {
+				return nil, fmt.Errorf("symbol %s has unknown kind %q", sym.Name, sym.Kind)
+			}
+		}
+		return symbols, nil
+	}
+	symbols := make([]Symbol, 0)
+	for i, line := range strings.Split(string(content), "\n") {
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("line %d: expected \"name address [kind]\"", i+1)
+		}
+		address, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid address %q", i+1, fields[1])
+		}
+		sym := Symbol{Name: fields[0], Address: address, Kind: SYM_VARIABLE}
+		if IsPredefined(sym.Name) {
+			sym.Kind = SYM_PREDEFINED
+		}
+		if len(fields) == 3 {
+			sym.Kind = fields[2]
+		}
+		if !validKind(sym.Kind) {
+			return nil, fmt.Errorf("line %d: unknown symbol kind %q", i+1, sym.Kind)
+		}
+		symbols = append(symbols, sym)
+	}
+	return symbols, nil
+}
+
+// IsPredefined 判断是否为 R0-R15、SP、SCREEN 等预定义符号
+func IsPredefined(name string) bool {
+	predefined := NewSymbolTable()
+	predefined.Init()
+	return predefined.Contains(name)
+}
+
+// predefinedSymbols 返回所有预定义符号的名字
+func predefinedSymbols() []string {
+	predefined := NewSymbolTable()
+	predefined.Init()
+	names := make([]string, 0, len(predefined.st))
+	for name := range predefined.st {
+		names = append(names, name)
+	}
+	return names
+}
+
+func validKind(kind string) bool {
+	return kind == SYM_LABEL || kind == SYM_VARIABLE || kind == SYM_PREDEFINED
+}
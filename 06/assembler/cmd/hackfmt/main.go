@@ -0,0 +1,91 @@
+// hackfmt 把 Hack 汇编文件改写为规范的格式，用法和 gofmt 类似：
+// 默认把结果输出到标准输出，-w 写回文件，-l 列出格式不规范的文件，-check 在有这样的文件时以状态 1 退出（用于 CI）。
+// 参数可以是文件或目录，目录中的 .asm 文件会被递归处理
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"hongkuancn/nand2tetris/assembler/asm"
+)
+
+func main() {
+	write := flag.Bool("w", false, "write the result back to the source file")
+	list := flag.Bool("l", false, "list files whose formatting differs from hackfmt's")
+	check := flag.Bool("check", false, "like -l, and exit with status 1 if any file is not formatted")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Println("usage: hackfmt [-w] [-l] [-check] <asm file or directory>...")
+		os.Exit(1)
+	}
+
+	failed, unformatted := false, false
+	for _, arg := range flag.Args() {
+		files, err := asmFiles(arg)
+		if err != nil {
+			fmt.Println(err)
+			failed = true
+			continue
+		}
+		for _, file := range files {
+			changed, err := format(file, *write, *list || *check)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+			unformatted = unformatted || changed
+		}
+	}
+	if failed || *check && unformatted {
+		os.Exit(1)
+	}
+}
+
+// asmFiles 返回 path 本身，或 path 目录下的所有 .asm 文件
+func asmFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	files := make([]string, 0)
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(file, ".asm") {
+			files = append(files, file)
+		}
+		return err
+	})
+	return files, err
+}
+
+// format 格式化一个文件，返回格式化的结果是否和原文件不同
+func format(file string, write bool, list bool) (bool, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	res, diags := asm.Format(src, file)
+	if diags.HasErrors() {
+		diags.Print(os.Stderr)
+		return false, fmt.Errorf("%s: not formatted because of syntax errors", file)
+	}
+	changed := !bytes.Equal(src, res)
+	if list && changed {
+		fmt.Println(file)
+	}
+	if write && changed {
+		return true, os.WriteFile(file, res, 0644)
+	}
+	if !write && !list {
+		os.Stdout.Write(res)
+	}
+	return changed, nil
+}
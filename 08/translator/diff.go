@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"hongkuancn/nand2tetris/assembler/asm"
+	"hongkuancn/nand2tetris/emulator/cpu"
+	"hongkuancn/nand2tetris/emulator/vm"
+)
+
+// 没有 Sys.init 时不引导，两边都按 VME 测试的习惯设置段的基地址
+var diffPointers = map[int]uint16{vm.SP: 256, vm.LCL: 300, vm.ARG: 400, vm.THIS: 3000, vm.THAT: 3010}
+
+// runDiff 是差分测试：同一个 .vm 程序直接在 VM 模拟器中执行，同时经过 CodeWriter、汇编器在 CPU 模拟器中执行，
+// 执行相同的 VM 命令数（或都执行完）后比较 RAM。不一致时自动删减程序，输出仍然不一致的最小程序
+func runDiff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	steps := flags.Uint64("steps", 100000, "compare after `n` VM commands, or when the program ends")
+	shrink := flags.Bool("shrink", true, "shrink a failing program to a minimal one")
+	flags.Parse(args)
+	if flags.NArg() < 1 {
+		fmt.Println("usage: translator -diff [-steps n] [-shrink=false] <vm file or directory>")
+		os.Exit(1)
+	}
+
+	prog, err := vm.ReadProgram(flags.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	diffs, err := diffCommands(prog.Commands, *steps)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(diffs) == 0 {
+		fmt.Println("VM emulator and translated assembly agree")
+		return
+	}
+	printDiffs(diffs)
+	if *shrink {
+		commands := shrinkCommands(prog.Commands, *steps)
+		fmt.Printf("\nminimal failing program (%d of %d commands):\n", len(commands), len(prog.Commands))
+		fmt.Print(formatCommands(commands))
+		diffs, _ = diffCommands(commands, *steps)
+		printDiffs(diffs)
+	}
+	os.Exit(1)
+}
+
+func printDiffs(diffs []string) {
+	for i, diff := range diffs {
+		if i == 20 {
+			fmt.Printf("... and %d more differences\n", len(diffs)-i)
+			break
+		}
+		fmt.Println(diff)
+	}
+}
+
+// sources 把命令按文件还原为 .vm 源码，相邻的同一文件的命令放在一起
+func sources(commands []vm.Command) []vm.Source {
+	srcs := make([]vm.Source, 0)
+	builder := strings.Builder{}
+	for i, cmd := range commands {
+		builder.WriteString(cmd.Text + "\n")
+		if i+1 == len(commands) || commands[i+1].File != cmd.File {
+			srcs = append(srcs, vm.Source{Name: cmd.File, Reader: strings.NewReader(builder.String())})
+			builder.Reset()
+		}
+	}
+	return srcs
+}
+
+func formatCommands(commands []vm.Command) string {
+	builder := strings.Builder{}
+	for i, cmd := range commands {
+		if i == 0 || commands[i-1].File != cmd.File {
+			builder.WriteString(fmt.Sprintf("// %s.vm\n", cmd.File))
+		}
+		builder.WriteString(cmd.Text + "\n")
+	}
+	return builder.String()
+}
+
+// translateCommands 用 CodeWriter 翻译命令，返回汇编和每条命令的代码在 ROM 中的起始地址（label 为 -1）。
+// CodeWriter 出错时会 panic，这里转成错误，也算作一处不一致
+func translateCommands(commands []vm.Command, bootstrap bool) (converted []byte, starts []int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	loopCnt = 0
+	writer := NewCodeWriter()
+	parser := NewParser("")
+	writer.setFunc("Sys.boot")
+	converted = make([]byte, 0)
+	if bootstrap {
+		converted = append(converted, writer.writeBootstrap("Sys.init", 0)...)
+	}
+	starts = make([]int, len(commands))
+	for i, cmd := range commands {
+		writer.setFile(cmd.File)
+		starts[i] = countInstructions(converted)
+		if cmd.Type == vm.C_LABEL {
+			starts[i] = -1
+		}
+		converted = append(converted, []byte(fmt.Sprintf("// %s\n", cmd.Text))...)
+		converted = append(converted, translateCommand(writer, parser, cmd.Text)...)
+	}
+	converted = append(converted, []byte("(END)\n@END\n0;JMP\n")...)
+	return converted, starts, nil
+}
+
+// countInstructions 数汇编中的指令数，也就是下一条指令的 ROM 地址
+func countInstructions(code []byte) int {
+	n := 0
+	for _, line := range strings.Split(string(code), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) > 0 && !strings.HasPrefix(line, "(") && !strings.HasPrefix(line, "//") {
+			n += 1
+		}
+	}
+	return n
+}
+
+// diffCommands 用两种方式执行命令并比较 RAM，返回所有不一致之处。程序本身不合法时返回错误
+func diffCommands(commands []vm.Command, steps uint64) ([]string, error) {
+	prog, err := vm.Load(sources(commands))
+	if err != nil {
+		return nil, err
+	}
+	_, bootstrap := prog.Functions["Sys.init"]
+	v := vm.New(prog)
+	if bootstrap {
+		if err := v.Bootstrap(); err != nil {
+			return nil, err
+		}
+	} else {
+		for address, value := range diffPointers {
+			v.Poke(address, value)
+		}
+	}
+	if _, err := v.Run(steps); err != nil {
+		return nil, err
+	}
+
+	code, starts, err := translateCommands(commands, bootstrap)
+	if err != nil {
+		return []string{fmt.Sprintf("translator failed: %v", err)}, nil
+	}
+	assembled, diags := asm.Assemble(bytes.NewReader(code), asm.Options{Name: "translated.asm", NoWarn: map[string]bool{}})
+	if diags.HasErrors() {
+		return []string{fmt.Sprintf("translated assembly does not assemble: %v", diags[0])}, nil
+	}
+	c := cpu.NewCPU()
+	if err := c.Load(assembled.Words()); err != nil {
+		return []string{fmt.Sprintf("translated assembly does not load: %v", err)}, nil
+	}
+	if !bootstrap {
+		for address, value := range diffPointers {
+			c.Poke(uint16(address), value)
+		}
+	}
+	isStart := make(map[uint16]bool)
+	for _, start := range starts {
+		if start >= 0 {
+			isStart[uint16(start)] = true
+		}
+	}
+
+	// 每到一条命令的起始地址就算执行完了前一条命令，执行完和 VM 相同的命令数时停下
+	executed := uint64(0)
+	limit := steps*1000 + 100000
+	for c.Cycles < limit && !c.Halted() {
+		if isStart[c.PC] {
+			if executed == v.Steps {
+				break
+			}
+			executed += 1
+		}
+		if err := c.Step(); err != nil {
+			return []string{fmt.Sprintf("CPU error after %d VM commands: %v", executed, err)}, nil
+		}
+	}
+	// 两边都停在死循环时，两种死循环检测可能相差一轮，命令数不必相同，只比较 RAM
+	diffs := make([]string, 0)
+	if executed != v.Steps && !(v.Halted() && c.Halted()) {
+		diffs = append(diffs, fmt.Sprintf("VM executed %d commands but the translated program executed %d in %d cycles", v.Steps, executed, c.Cycles))
+	}
+	return append(diffs, compareRAM(v, c, assembled.Symbols, bootstrap)...), nil
+}
+
+// compareRAM 比较段指针、temp、static（按名字，两边地址可能不同）、栈中 SP 以下的部分和 2048 以上的内存。
+// R13-R15 是翻译器的临时变量，栈中保存返回地址的位置两边的值本来就不同（命令下标和 ROM 地址），都不比较
+func compareRAM(v *vm.VM, c *cpu.CPU, symbols *asm.SymbolTable, bootstrap bool) []string {
+	diffs := make([]string, 0)
+	check := func(name string, vmValue uint16, asmValue uint16) {
+		if vmValue != asmValue {
+			diffs = append(diffs, fmt.Sprintf("%s: vm %d, asm %d", name, int16(vmValue), int16(asmValue)))
+		}
+	}
+	names := []string{"SP", "LCL", "ARG", "THIS", "THAT"}
+	for a := 0; a <= 12; a++ {
+		name := fmt.Sprintf("temp %d (RAM[%d])", a-vm.TEMP, a)
+		if a < len(names) {
+			name = names[a]
+		}
+		check(name, v.RAM[a], c.RAM[a])
+	}
+
+	statics := make([]string, 0, len(v.Program.Statics))
+	for name := range v.Program.Statics {
+		statics = append(statics, name)
+	}
+	sort.Strings(statics)
+	for _, name := range statics {
+		address := symbols.GetAddress(name)
+		if address < 0 {
+			diffs = append(diffs, fmt.Sprintf("static %s is missing in the translated program", name))
+			continue
+		}
+		check("static "+name, v.RAM[v.Program.Statics[name]], c.RAM[address])
+	}
+
+	returns := make(map[int]bool)
+	frames := len(v.Frames)
+	if !bootstrap {
+		frames -= 1
+	}
+	lcl := int(v.RAM[vm.LCL])
+	for i := 0; i < frames && lcl >= 5; i++ {
+		returns[lcl-5] = true
+		lcl = int(v.RAM[lcl-4])
+	}
+	for a := vm.STACK; a < int(v.RAM[vm.SP]) && a < 2048; a++ {
+		if !returns[a] {
+			check(fmt.Sprintf("stack RAM[%d]", a), v.RAM[a], c.RAM[a])
+		}
+	}
+	for a := 2048; a < cpu.KBD; a++ {
+		check(fmt.Sprintf("RAM[%d]", a), v.RAM[a], c.RAM[a])
+	}
+	return diffs
+}
+
+// failure 把不一致分类，删减程序时要保持同一类失败，不然容易删成另一个无关的错误
+func failure(diffs []string) string {
+	if len(diffs) == 0 {
+		return ""
+	}
+	for _, prefix := range []string{"translator failed", "translated assembly", "CPU error", "VM executed"} {
+		if strings.HasPrefix(diffs[0], prefix) {
+			return prefix
+		}
+	}
+	return "RAM"
+}
+
+// shrinkCommands 反复删去一段命令，只要删完仍是合法的程序且仍有同一类不一致就保留删除，删除的长度从一半逐步减到一条
+func shrinkCommands(commands []vm.Command, steps uint64) []vm.Command {
+	diffs, _ := diffCommands(commands, steps)
+	want := failure(diffs)
+	fails := func(candidate []vm.Command) bool {
+		diffs, err := diffCommands(candidate, steps)
+		return err == nil && failure(diffs) == want
+	}
+	for chunk := len(commands) / 2; chunk >= 1; chunk /= 2 {
+		for i := 0; i < len(commands); {
+			end := i + chunk
+			if end > len(commands) {
+				end = len(commands)
+			}
+			candidate := append(append([]vm.Command{}, commands[:i]...), commands[end:]...)
+			if len(candidate) > 0 && fails(candidate) {
+				commands = candidate
+			} else {
+				i += chunk
+			}
+		}
+	}
+	return commands
+}
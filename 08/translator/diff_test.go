@@ -0,0 +1,80 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hongkuancn/nand2tetris/emulator/vm"
+)
+
+// ROOT 是仓库的根目录，go test 在包所在的目录中执行
+const ROOT = "../.."
+
+// TestDiffCommands 一对 .vm 文件（有 Sys.init，需要引导）翻译后和 VM 模拟器的结果一致。
+// Main.max 在 return 之后还有 label，翻译时要算在 Main.max 中
+func TestDiffCommands(t *testing.T) {
+	prog, err := vm.Load([]vm.Source{
+		{Name: "Sys", Reader: strings.NewReader(`function Sys.init 0
+push constant 8
+push constant 3
+call Main.max 2
+pop static 0
+label LOOP
+goto LOOP
+`)},
+		{Name: "Main", Reader: strings.NewReader(`function Main.max 1
+push argument 0
+push argument 1
+gt
+if-goto FIRST
+push argument 1
+return
+label FIRST
+push argument 0
+return
+`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := diffCommands(prog.Commands, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) > 0 {
+		t.Fatalf("VM emulator and translated assembly disagree:\n%s", strings.Join(diffs, "\n"))
+	}
+}
+
+// TestDiffCoursePrograms 07、08 中的程序翻译后和 VM 模拟器的结果一致
+func TestDiffCoursePrograms(t *testing.T) {
+	dirs := make([]string, 0)
+	for _, pattern := range []string{"07/*/*/*.vm", "08/*/*/*.vm"} {
+		matches, err := filepath.Glob(filepath.Join(ROOT, pattern))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range matches {
+			if len(dirs) == 0 || dirs[len(dirs)-1] != filepath.Dir(file) {
+				dirs = append(dirs, filepath.Dir(file))
+			}
+		}
+	}
+	if len(dirs) == 0 {
+		t.Fatal("no course programs found")
+	}
+	for _, dir := range dirs {
+		prog, err := vm.ReadProgram(dir)
+		if err != nil {
+			t.Fatalf("%s: %v", dir, err)
+		}
+		diffs, err := diffCommands(prog.Commands, 100000)
+		if err != nil {
+			t.Fatalf("%s: %v", dir, err)
+		}
+		if len(diffs) > 0 {
+			t.Errorf("%s: VM emulator and translated assembly disagree:\n%s", dir, strings.Join(diffs, "\n"))
+		}
+	}
+}
@@ -1,3 +1,13 @@
 module hongkuancn/nand2tetris/translator
 
 go 1.20
+
+require (
+	hongkuancn/nand2tetris/assembler v0.0.0
+	hongkuancn/nand2tetris/emulator v0.0.0
+)
+
+replace (
+	hongkuancn/nand2tetris/assembler => ../../06/assembler
+	hongkuancn/nand2tetris/emulator => ../../05/emulator
+)
@@ -24,8 +24,13 @@ const (
 var loopCnt int
 
 func main() {
+	if len(os.Args) >= 2 && os.Args[1] == "-diff" {
+		runDiff(os.Args[2:])
+		return
+	}
 	if len(os.Args) < 2 {
-		fmt.Println("usage: assembler <asm file>")
+		fmt.Println("usage: translator <vm file or directory>")
+		fmt.Println("       translator -diff [-steps n] [-shrink=false] <vm file or directory>")
 		os.Exit(1)
 	}
 
@@ -64,18 +69,27 @@ func main() {
 
 	converted := make([]byte, 0)
 
-	writer.setFunc("Sys.boot")
-	converted = append(converted, writer.writeBootstrap("Sys.init", 0)...)
-
-	//converted = append(converted, []byte("@256\nD=A\n@SP\nM=D\n")...)
+	parsers := make([]*Parser, 0, len(fileNames))
 	for _, fileName := range fileNames {
-		writer.setFile(fileName[:len(fileName)-3])
 		parser := NewParser(filepath.Join(dir, fileName))
 		err = parser.read()
 		if err != nil {
 			fmt.Printf("%v", err)
 			return
 		}
+		parsers = append(parsers, parser)
+	}
+
+	// 只有定义了 Sys.init 的程序才需要引导，07 的测试程序由 .tst 脚本自己设置 SP
+	writer.setFunc("Sys.boot")
+	if hasSysInit(parsers) {
+		converted = append(converted, writer.writeBootstrap("Sys.init", 0)...)
+	}
+
+	//converted = append(converted, []byte("@256\nD=A\n@SP\nM=D\n")...)
+	for i, fileName := range fileNames {
+		writer.setFile(fileName[:len(fileName)-3])
+		parser := parsers[i]
 
 		for _, line := range parser.lines {
 			line = strings.TrimSpace(line)
@@ -88,32 +102,7 @@ func main() {
 			line = split[0]
 			line = strings.TrimSpace(line)
 
-			typ := parser.commandType(line)
-			if typ == C_ARITHMETIC {
-				converted = append(converted, writer.writeArithmetic(parser.arg1(line))...)
-			} else if typ == C_PUSH {
-				converted = append(converted, writer.writePushPop(C_PUSH, parser.arg1(line), parser.arg2(line))...)
-			} else if typ == C_POP {
-				converted = append(converted, writer.writePushPop(C_POP, parser.arg1(line), parser.arg2(line))...)
-			} else if typ == C_LABEL {
-				converted = append(converted, writer.writeLabel(parser.arg1(line))...)
-			} else if typ == C_IF {
-				converted = append(converted, writer.writeIf(parser.arg1(line))...)
-			} else if typ == C_GOTO {
-				converted = append(converted, writer.writeGoto(parser.arg1(line))...)
-			} else if typ == C_FUNCTION {
-				funcName := parser.arg1(line)
-				writer.setFunc(funcName)
-				_, ok := writer.retMap[funcName]
-				if !ok {
-					writer.retMap[funcName] = 0
-				}
-				converted = append(converted, writer.writeFunction(funcName, parser.arg2(line))...)
-			} else if typ == C_RETURN {
-				converted = append(converted, writer.writeReturn()...)
-			} else if typ == C_CALL {
-				converted = append(converted, writer.writeCall(parser.arg1(line), parser.arg2(line))...)
-			}
+			converted = append(converted, translateCommand(writer, parser, line)...)
 		}
 	}
 
@@ -129,6 +118,51 @@ func main() {
 	}
 }
 
+// hasSysInit 判断程序中是否有 function Sys.init
+func hasSysInit(parsers []*Parser) bool {
+	for _, parser := range parsers {
+		for _, line := range parser.lines {
+			line = strings.TrimSpace(strings.Split(line, "//")[0])
+			if len(line) > 0 && parser.commandType(line) == C_FUNCTION && parser.arg1(line) == "Sys.init" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// translateCommand 把一条去掉了注释的 VM 命令翻译为汇编
+func translateCommand(writer *CodeWriter, parser *Parser, line string) []byte {
+	converted := make([]byte, 0)
+	typ := parser.commandType(line)
+	if typ == C_ARITHMETIC {
+		converted = append(converted, writer.writeArithmetic(parser.arg1(line))...)
+	} else if typ == C_PUSH {
+		converted = append(converted, writer.writePushPop(C_PUSH, parser.arg1(line), parser.arg2(line))...)
+	} else if typ == C_POP {
+		converted = append(converted, writer.writePushPop(C_POP, parser.arg1(line), parser.arg2(line))...)
+	} else if typ == C_LABEL {
+		converted = append(converted, writer.writeLabel(parser.arg1(line))...)
+	} else if typ == C_IF {
+		converted = append(converted, writer.writeIf(parser.arg1(line))...)
+	} else if typ == C_GOTO {
+		converted = append(converted, writer.writeGoto(parser.arg1(line))...)
+	} else if typ == C_FUNCTION {
+		funcName := parser.arg1(line)
+		writer.setFunc(funcName)
+		_, ok := writer.retMap[funcName]
+		if !ok {
+			writer.retMap[funcName] = 0
+		}
+		converted = append(converted, writer.writeFunction(funcName, parser.arg2(line))...)
+	} else if typ == C_RETURN {
+		converted = append(converted, writer.writeReturn()...)
+	} else if typ == C_CALL {
+		converted = append(converted, writer.writeCall(parser.arg1(line), parser.arg2(line))...)
+	}
+	return converted
+}
+
 type Parser struct {
 	file  string
 	lines []string
@@ -217,10 +251,6 @@ func (c *CodeWriter) setFunc(fn string) {
 	c.funcs = append(c.funcs, fn)
 }
 
-func (c *CodeWriter) returnFunc() {
-	c.funcs = c.funcs[:len(c.funcs)-1]
-}
-
 // 当前的函数
 func (c *CodeWriter) curFunc() string {
 	return c.funcs[len(c.funcs)-1]
@@ -228,7 +258,6 @@ func (c *CodeWriter) curFunc() string {
 
 func (c *CodeWriter) writeArithmetic(command string) []byte {
 	res := make([]byte, 0)
-	println(command)
 	if command == "neg" || command == "not" {
 		res = append(res, []byte("@SP\nM=M-1\nA=M\n")...)
 		switch command {
@@ -383,8 +412,8 @@ func (c *CodeWriter) writeReturn() []byte {
 	builder.WriteString("// pop THIS\n@R15\nAM=M-1\nD=M\n@THIS\nM=D\n")
 	builder.WriteString("// pop ARG\n@R15\nAM=M-1\nD=M\n@ARG\nM=D\n")
 	builder.WriteString("// pop LCL\n@R15\nAM=M-1\nD=M\n@LCL\nM=D\n")
+	// return 之后的 label 仍属于当前函数，直到下一条 function 命令
 	builder.WriteString("@R14\nA=M\n0;JMP\n")
-	c.returnFunc()
 	return []byte(builder.String())
 }
 